@@ -16,14 +16,26 @@ limitations under the License.
 package main
 
 import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"io"
 	"os"
+	"os/signal"
+	"sort"
+	"strconv"
 	"strings"
+	"text/tabwriter"
+	"time"
 
 	"github.com/venicegeo/bf-client/client"
 
 	"gopkg.in/urfave/cli.v1"
+	"gopkg.in/yaml.v2"
 )
 
 func main() {
@@ -42,13 +54,53 @@ func main() {
 				Name:  "download,d",
 				Usage: "downoad a scene from a catalog",
 			},
+			cli.BoolFlag{
+				Name:  "search,s",
+				Usage: "search a catalog for scenes",
+			},
+			cli.Float64Flag{
+				Name:  "cloud-cover",
+				Usage: "maximum percent cloud cover (with --search)",
+			},
+			cli.StringFlag{
+				Name:  "bbox",
+				Usage: "bounding box as minx,miny,maxx,maxy (with --search)",
+			},
+			cli.StringFlag{
+				Name:  "acquired-after",
+				Usage: "earliest acquisition date, e.g. 2017-06-01 (with --search)",
+			},
+			cli.StringFlag{
+				Name:  "acquired-before",
+				Usage: "latest acquisition date, e.g. 2017-07-01 (with --search)",
+			},
+			cli.IntFlag{
+				Name:  "limit",
+				Usage: "maximum number of scenes returned, 0 for all (with --search, default: all)",
+			},
+			cli.IntFlag{
+				Name:  "workers",
+				Value: 4,
+				Usage: "number of bands downloaded at once (with --download)",
+			},
+			cli.StringFlag{
+				Name:  "provider",
+				Usage: "where scenes come from: " + strings.Join(client.ProviderNames(), ", ") + " (default: " + client.DefaultProvider + ")",
+			},
 		},
 		Action: func(c *cli.Context) error {
+			client.SetOverride("provider", c.String("provider"))
+
 			info := c.IsSet("info")
 			download := c.IsSet("download")
+			search := c.IsSet("search")
+
+			if countTrue(info, download, search) != 1 {
+				return cli.NewExitError("catalog: exactly one of --info, --download and --search is required", 2)
+			}
 
 			switch {
-			case info && !download:
+			case info:
 				arg, err := getZeroOrOneArg("catalog info", c)
 				if err != nil {
 					return err
@@ -61,14 +113,27 @@ func main() {
 				default:
 					return runCatalogInfoForCatalog(arg)
 				}
-			case !info && download:
+			case download:
 				arg, err := getOneArg("catalog download", c)
 				if err != nil {
 					return err
 				}
-				return runCatalogSceneDownload(arg)
+				opts, err := getDownloadOptions(c)
+				if err != nil {
+					return err
+				}
+				opts.Workers = c.Int("workers")
+				return runCatalogSceneDownload(arg, opts)
 			default:
-				return cli.NewExitError("catalog: exactly one of --info and --download is required", 2)
+				arg, err := getOneArg("catalog search", c)
+				if err != nil {
+					return err
+				}
+				query, err := getSearchQuery(c)
+				if err != nil {
+					return err
+				}
+				return runCatalogSearch(arg, query)
 			}
 		},
 	}
@@ -89,16 +154,62 @@ func main() {
 			},
 			cli.BoolFlag{
 				Name:  "delete,d",
-				Usage: "delete a job",
+				Usage: "delete one or more jobs, by id or by --status/--older-than",
+			},
+			cli.BoolFlag{
+				Name:  "wait,w",
+				Usage: "wait for a job to finish, printing its status as it changes",
+			},
+			cli.StringFlag{
+				Name:  "algorithm,a",
+				Usage: "service id of the algorithm to run (with --submit)",
+			},
+			cli.StringFlag{
+				Name:  "name,n",
+				Usage: "name of the new job (with --submit)",
+			},
+			cli.BoolTFlag{
+				Name:  "compute-mask",
+				Usage: "have the job compute the cloud mask; --compute-mask=false to skip it (with --submit)",
+			},
+			cli.StringFlag{
+				Name:  "status",
+				Usage: "delete all jobs with this status, e.g. Error (with --delete)",
+			},
+			cli.StringFlag{
+				Name:  "older-than",
+				Usage: "delete all jobs older than this age, e.g. 30d or 12h (with --delete)",
+			},
+			cli.BoolFlag{
+				Name:  "yes,y",
+				Usage: "do not ask for confirmation (with --delete)",
+			},
+			cli.DurationFlag{
+				Name:  "interval",
+				Value: 10 * time.Second,
+				Usage: "time between status checks (with --wait)",
+			},
+			cli.DurationFlag{
+				Name:  "timeout",
+				Usage: "give up waiting after this long, e.g. 30m (with --wait, default: never)",
+			},
+			cli.BoolFlag{
+				Name:  "coastline",
+				Usage: "download the coastline once the job succeeds (with --wait)",
 			},
 		},
 		Action: func(c *cli.Context) error {
 			info := c.IsSet("info")
 			submit := c.IsSet("submit")
 			delete := c.IsSet("delete")
+			wait := c.IsSet("wait")
+
+			if countTrue(info, submit, delete, wait) != 1 {
+				return cli.NewExitError("job: exactly one of --info, --submit, --delete and --wait is required", 2)
+			}
 
 			switch {
-			case info && !submit && !delete:
+			case info:
 				arg, err := getZeroOrOneArg("job info", c)
 				if err != nil {
 					return err
@@ -108,16 +219,42 @@ func main() {
 				} else {
 					return runJobInfoForJob(arg)
 				}
-			case !info && submit && !delete:
-				return runJobSubmit()
-			case !info && !submit && delete:
-				arg, err := getOneArg("job deletion", c)
+			case submit:
+				arg, err := getOneArg("job submission", c)
+				if err != nil {
+					return err
+				}
+				return runJobSubmit(arg, c.String("algorithm"), c.String("name"), c.BoolT("compute-mask"))
+			case delete:
+				filter, err := getJobFilter(c)
 				if err != nil {
 					return err
 				}
-				return runJobDelete(arg)
+				switch {
+				case filter != nil && c.NArg() == 0:
+					return runJobDeleteByFilter(filter, c.Bool("yes"))
+				case filter == nil && c.NArg() > 0:
+					return runJobDelete(c.Args(), c.Bool("yes"))
+				default:
+					return cli.NewExitError("job deletion: either job ids or --status/--older-than is required", 2)
+				}
 			default:
-				return cli.NewExitError("job: exactly one of --info and --submit is required", 2)
+				arg, err := getOneArg("job wait", c)
+				if err != nil {
+					return err
+				}
+				opts := &client.WaitOptions{
+					Interval: c.Duration("interval"),
+					Timeout:  c.Duration("timeout"),
+				}
+				var download *client.DownloadOptions
+				if c.Bool("coastline") {
+					download, err = getDownloadOptions(c)
+					if err != nil {
+						return err
+					}
+				}
+				return runJobWait(arg, opts, download)
 			}
 		},
 	}
@@ -142,7 +279,11 @@ func main() {
 				if err != nil {
 					return err
 				}
-				return runCoastlineDownload(arg)
+				opts, err := getDownloadOptions(c)
+				if err != nil {
+					return err
+				}
+				return runCoastlineDownload(arg, opts)
 			default:
 				return cli.NewExitError("coastline: --download is required", 2)
 			}
@@ -180,18 +321,216 @@ func main() {
 		},
 	}
 
+	configCommand := cli.Command{
+		Name:  "config",
+		Usage: "view and edit the settings",
+
+		Subcommands: []cli.Command{
+			{
+				Name:  "init",
+				Usage: "create or replace a profile, prompting for each setting",
+				Action: func(c *cli.Context) error {
+					return runConfigInit()
+				},
+			},
+			{
+				Name:  "show",
+				Usage: "show the settings and where each came from, with secrets redacted",
+				Action: func(c *cli.Context) error {
+					return runConfigShow()
+				},
+			},
+			{
+				Name:      "get",
+				Usage:     "print one resolved setting",
+				ArgsUsage: "<field>",
+				Action: func(c *cli.Context) error {
+					arg, err := getOneArg("config get", c)
+					if err != nil {
+						return err
+					}
+					return runConfigGet(arg)
+				},
+			},
+			{
+				Name:      "set",
+				Usage:     "change one setting in .beachfrontrc",
+				ArgsUsage: "<field> <value>",
+				Action: func(c *cli.Context) error {
+					if c.NArg() != 2 {
+						return cli.NewExitError("config set: exactly two arguments are required", 2)
+					}
+					return runConfigSet(c.Args().Get(0), c.Args().Get(1))
+				},
+			},
+			{
+				Name:  "validate",
+				Usage: "check that the settings are complete and well formed",
+				Action: func(c *cli.Context) error {
+					return runConfigValidate()
+				},
+			},
+		},
+	}
+
+	catalogCommand.Flags = append(catalogCommand.Flags, downloadFlags(client.SceneTemplate)...)
+	coastlineCommand.Flags = append(coastlineCommand.Flags, downloadFlags(client.CoastlineTemplate)...)
+	jobCommand.Flags = append(jobCommand.Flags, downloadFlags(client.CoastlineTemplate)...)
+
 	app := cli.NewApp()
 	app.Name = "beachfront"
 	app.Usage = "access the Beachfront services"
 
+	app.Flags = []cli.Flag{
+		cli.StringFlag{
+			Name:   "profile,p",
+			EnvVar: client.ProfileEnvVar,
+			Usage:  "the .beachfrontrc profile to use",
+		},
+		cli.StringFlag{
+			Name:  "domain",
+			Usage: "the Beachfront domain, overriding $BEACHFRONT_DOMAIN and .beachfrontrc",
+		},
+		cli.StringFlag{
+			Name:  "auth",
+			Usage: "the Beachfront API key, overriding $BEACHFRONT_AUTH and .beachfrontrc",
+		},
+		cli.StringFlag{
+			Name:  "planet-key",
+			Usage: "the Planet API key, overriding $BEACHFRONT_PLANET_KEY and .beachfrontrc",
+		},
+		cli.StringFlag{
+			Name:  "api-url",
+			Usage: "the bf-api base URL, by default https://bf-api.<domain>",
+		},
+		cli.StringFlag{
+			Name:  "broker-url",
+			Usage: "the bf-ia-broker base URL, by default https://bf-ia-broker.<domain>",
+		},
+		cli.StringFlag{
+			Name:  "catalog-auth",
+			Usage: "how the Planet key is sent to the broker: header (default), basic, or query for a broker that takes only PL_API_KEY in the URL",
+		},
+		cli.StringFlag{
+			Name:  "stac-url",
+			Usage: "the STAC API base URL, for the stac catalog provider",
+		},
+		cli.StringFlag{
+			Name:  "local-dir",
+			Usage: "the directory of scenes, for the local catalog provider; downloads are hard links to its files where possible, so don't edit them in place",
+		},
+		cli.BoolFlag{
+			Name:  "verbose",
+			Usage: "log each request: method, URL, status, time and size",
+		},
+		cli.BoolFlag{
+			Name:  "debug",
+			Usage: "log each request with its headers, and every retry",
+		},
+		cli.IntFlag{
+			Name:  "retries",
+			Value: client.DefaultRetryPolicy.MaxAttempts - 1,
+			Usage: "times to retry a request that failed for a transient reason",
+		},
+		cli.StringFlag{
+			Name:  "format,f",
+			Value: formatText,
+			Usage: "output format: text, json, yaml, csv or table",
+		},
+	}
+
+	app.Before = func(c *cli.Context) error {
+		switch {
+		case c.GlobalBool("debug"):
+			logLevel = client.LogDebug
+		case c.GlobalBool("verbose"):
+			logLevel = client.LogVerbose
+		}
+		client.SetLogLevel(logLevel)
+
+		client.SelectProfile(c.GlobalString("profile"))
+		client.SetOverrides(map[string]string{
+			"domain":       c.GlobalString("domain"),
+			"auth":         c.GlobalString("auth"),
+			"planet_key":   c.GlobalString("planet-key"),
+			"api_url":      c.GlobalString("api-url"),
+			"broker_url":   c.GlobalString("broker-url"),
+			"catalog_auth": c.GlobalString("catalog-auth"),
+			"stac_url":     c.GlobalString("stac-url"),
+			"local_dir":    c.GlobalString("local-dir"),
+		})
+
+		if c.GlobalIsSet("retries") {
+			retries := c.GlobalInt("retries")
+			if retries < 0 {
+				return cli.NewExitError("beachfront: --retries must not be negative", 2)
+			}
+			ctx = client.WithRetryPolicy(ctx, &client.RetryPolicy{MaxAttempts: retries + 1})
+		}
+
+		outputFormat = c.GlobalString("format")
+		switch outputFormat {
+		case formatText, formatJSON, formatYAML, formatCSV, formatTable:
+			return nil
+		}
+		return cli.NewExitError("beachfront: --format must be text, json, yaml, csv or table", 2)
+	}
+
 	app.Commands = []cli.Command{
 		catalogCommand,
 		jobCommand,
 		coastlineCommand,
 		algorithmCommand,
+		configCommand,
 	}
 
-	app.Run(os.Args)
+	var stop context.CancelFunc
+	ctx, stop = signal.NotifyContext(context.Background(), os.Interrupt)
+	go func() {
+		// after the first interrupt, a second one exits at once
+		<-ctx.Done()
+		stop()
+	}()
+
+	err := app.Run(os.Args)
+	if err != nil {
+		exitWithError(err)
+	}
+}
+
+// cancelled by an interrupt, to stop requests and downloads in progress
+var ctx = context.Background()
+
+// set by --verbose and --debug
+var logLevel = client.LogQuiet
+
+// exit codes for failures, besides those of job --wait
+const (
+	exitUnauthorized = 5
+	exitNotFound     = 6
+	exitRateLimited  = 7
+	exitServerError  = 8
+	exitInterrupted  = 130 // as for SIGINT in the shell
+)
+
+// handles the errors not already handled as a cli.ExitError
+func exitWithError(err error) {
+	code := 1
+	switch {
+	case errors.Is(err, context.Canceled):
+		fmt.Fprintln(os.Stderr, "beachfront: interrupted")
+		os.Exit(exitInterrupted)
+	case errors.Is(err, client.ErrUnauthorized):
+		code = exitUnauthorized
+	case errors.Is(err, client.ErrNotFound):
+		code = exitNotFound
+	case errors.Is(err, client.ErrRateLimited):
+		code = exitRateLimited
+	case errors.Is(err, client.ErrServer):
+		code = exitServerError
+	}
+	fmt.Fprintln(os.Stderr, "beachfront: "+err.Error())
+	os.Exit(code)
 }
 
 //---------------------------------------------------------------------
@@ -208,7 +547,6 @@ func getZeroOrOneArg(area string, c *cli.Context) (string, error) {
 }
 
 func getOneArg(area string, c *cli.Context) (string, error) {
-	log.Print(c.Args())
 	switch c.NArg() {
 	case 1:
 		return c.Args().Get(0), nil
@@ -217,6 +555,181 @@ func getOneArg(area string, c *cli.Context) (string, error) {
 	}
 }
 
+func countTrue(flags ...bool) int {
+	n := 0
+	for _, flag := range flags {
+		if flag {
+			n++
+		}
+	}
+	return n
+}
+
+// the flags shared by every --download
+func downloadFlags(template string) []cli.Flag {
+	return []cli.Flag{
+		cli.StringFlag{
+			Name:  "output-dir,o",
+			Value: ".",
+			Usage: "directory downloads are written to",
+		},
+		cli.StringFlag{
+			Name:  "template",
+			Value: template,
+			Usage: "downloaded file name, may include directories and {variables}",
+		},
+		cli.StringFlag{
+			Name:  "existing",
+			Value: string(client.ExistingFail),
+			Usage: "if a downloaded file already exists: skip, overwrite or fail",
+		},
+	}
+}
+
+func getDownloadOptions(c *cli.Context) (*client.DownloadOptions, error) {
+	existing := client.ExistingPolicy(c.String("existing"))
+	switch existing {
+	case client.ExistingSkip, client.ExistingOverwrite, client.ExistingFail:
+	default:
+		return nil, cli.NewExitError("download: --existing must be skip, overwrite or fail", 2)
+	}
+
+	return &client.DownloadOptions{
+		Progress:  newProgressDisplay(),
+		OutputDir: c.String("output-dir"),
+		Template:  c.String("template"),
+		Existing:  existing,
+	}, nil
+}
+
+func getSearchQuery(c *cli.Context) (*client.SearchQuery, error) {
+	query := &client.SearchQuery{
+		Limit: c.Int("limit"),
+	}
+	if query.Limit < 0 {
+		return nil, cli.NewExitError("catalog search: --limit must not be negative", 2)
+	}
+	if c.IsSet("cloud-cover") {
+		query.MaxCloudCover = client.CloudCover(c.Float64("cloud-cover"))
+	}
+
+	if s := c.String("bbox"); s != "" {
+		parts := strings.Split(s, ",")
+		if len(parts) != 4 {
+			return nil, cli.NewExitError("catalog search: --bbox must be minx,miny,maxx,maxy", 2)
+		}
+		query.Bbox = make([]float64, 4)
+		for i, part := range parts {
+			v, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+			if err != nil {
+				return nil, cli.NewExitError("catalog search: invalid --bbox value: "+part, 2)
+			}
+			query.Bbox[i] = v
+		}
+	}
+
+	var err error
+	query.AcquiredAfter, err = parseDate(c.String("acquired-after"))
+	if err != nil {
+		return nil, cli.NewExitError("catalog search: "+err.Error(), 2)
+	}
+	query.AcquiredBefore, err = parseDate(c.String("acquired-before"))
+	if err != nil {
+		return nil, cli.NewExitError("catalog search: "+err.Error(), 2)
+	}
+
+	return query, nil
+}
+
+// accepts "2017-06-01" or a full RFC 3339 timestamp; "" is the zero time
+func parseDate(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse("2006-01-02", s)
+	if err == nil {
+		return t, nil
+	}
+	t, err = time.Parse(time.RFC3339, s)
+	if err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid date: '%s'", s)
+}
+
+func getJobFilter(c *cli.Context) (*client.JobFilter, error) {
+	status := c.String("status")
+	olderThan := c.String("older-than")
+	if status == "" && olderThan == "" {
+		return nil, nil
+	}
+
+	filter := &client.JobFilter{Status: status}
+
+	if olderThan != "" {
+		age, err := parseAge(olderThan)
+		if err != nil {
+			return nil, cli.NewExitError("job deletion: "+err.Error(), 2)
+		}
+		filter.OlderThan = age
+	}
+
+	return filter, nil
+}
+
+// like time.ParseDuration, but also allows days: "30d"
+func parseAge(s string) (time.Duration, error) {
+	if strings.HasSuffix(s, "d") {
+		days, err := strconv.Atoi(strings.TrimSuffix(s, "d"))
+		if err != nil || days < 0 {
+			return 0, fmt.Errorf("invalid age: '%s'", s)
+		}
+		return time.Duration(days) * 24 * time.Hour, nil
+	}
+
+	age, err := time.ParseDuration(s)
+	if err != nil || age < 0 {
+		return 0, fmt.Errorf("invalid age: '%s'", s)
+	}
+	return age, nil
+}
+
+var stdin = bufio.NewReader(os.Stdin)
+
+// asks on stdin, returning true only for "y" or "yes". Prompts go to
+// stderr, keeping stdout for the command's output.
+func confirm(prompt string) bool {
+	fmt.Fprintf(os.Stderr, "%s [y/N]: ", prompt)
+
+	answer, err := stdin.ReadString('\n')
+	if err != nil {
+		return false
+	}
+
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+// asks on stdin, returning the answer or, if it is empty, the default
+func ask(prompt string, def string) (string, error) {
+	if def != "" {
+		fmt.Fprintf(os.Stderr, "%s [%s]: ", prompt, def)
+	} else {
+		fmt.Fprintf(os.Stderr, "%s: ", prompt)
+	}
+
+	answer, err := stdin.ReadString('\n')
+	if err != nil && answer == "" {
+		return "", err
+	}
+
+	answer = strings.TrimSpace(answer)
+	if answer == "" {
+		return def, nil
+	}
+	return answer, nil
+}
+
 func newCatalogClient() (*client.CatalogClient, error) {
 
 	c, err := client.NewCatalogClient()
@@ -272,14 +785,12 @@ func runCatalogInfoForScene(id string) error {
 	if err != nil {
 		return err
 	}
-	s, err := c.GetInfoForScene(id)
+	feature, err := c.GetInfoForSceneContext(ctx, id)
 	if err != nil {
 		return err
 	}
 
-	fmt.Print(s)
-
-	return nil
+	return renderOne(feature.String()+"\n", catalogFeatureRecord(feature))
 }
 
 func runCatalogInfoForCatalog(id string) error {
@@ -287,31 +798,47 @@ func runCatalogInfoForCatalog(id string) error {
 	if err != nil {
 		return err
 	}
-	s, err := c.GetInfoForCatalog(id)
+	catalog, err := c.GetInfoForCatalogContext(ctx, id)
 	if err != nil {
 		return err
 	}
 
-	fmt.Print(s)
-
-	return nil
+	return renderList(catalog.String(), catalogColumns, catalogRecords(catalog))
 }
 
-func runCatalogSceneDownload(id string) error {
+func runCatalogSearch(id string, query *client.SearchQuery) error {
 	c, err := newCatalogClient()
 	if err != nil {
 		return err
 	}
-	info, err := c.DoCatalogSceneDownload(id)
+	catalog, err := c.SearchCatalogContext(ctx, id, query)
 	if err != nil {
 		return err
 	}
 
-	for k, v := range info {
-		fmt.Printf("%s: %d bytes\n", k, v)
+	return renderList(catalog.String(), catalogColumns, catalogRecords(catalog))
+}
+
+func runCatalogSceneDownload(id string, opts *client.DownloadOptions) error {
+	c, err := newCatalogClient()
+	if err != nil {
+		return err
 	}
 
-	return nil
+	// on partial failure, still report the bands that did finish
+	results, err := c.DoCatalogSceneDownloadContext(ctx, id, opts)
+
+	text := ""
+	records := make([]record, len(results))
+	for i, result := range results {
+		text += result.String() + "\n"
+		records[i] = downloadRecord(result)
+	}
+	rerr := renderList(text, downloadColumns, records)
+	if err != nil {
+		return err
+	}
+	return rerr
 }
 
 func runJobInfoForJobs() error {
@@ -319,12 +846,16 @@ func runJobInfoForJobs() error {
 	if err != nil {
 		return err
 	}
-	s, err := c.GetInfoForJobs()
+	jobs, err := c.GetInfoForJobsContext(ctx)
 	if err != nil {
 		return err
 	}
-	fmt.Print(s)
-	return nil
+
+	records := make([]record, len(jobs.Features))
+	for i, job := range jobs.Features {
+		records[i] = jobRecord(job)
+	}
+	return renderList(jobs.String(), jobColumns, records)
 }
 
 func runJobInfoForJob(id string) error {
@@ -332,46 +863,339 @@ func runJobInfoForJob(id string) error {
 	if err != nil {
 		return err
 	}
-	s, err := c.GetInfoForJob(id)
+	job, err := c.GetInfoForJobContext(ctx, id)
 	if err != nil {
 		return err
 	}
-	fmt.Print(s)
-	return nil
+	return renderOne(job.String(), jobRecord(job))
+}
+
+// submits the job with the planet_key setting, which the global
+// --planet-key overrides
+func runJobSubmit(sceneId string, algorithmId string, name string, computeMask bool) error {
+	settings, err := client.LoadSettings()
+	if err != nil {
+		return err
+	}
+
+	c, err := client.NewJobClientWithSettings(settings)
+	if err != nil {
+		return err
+	}
+
+	fields, err := settings.Require([]string{"planet_key"})
+	if err != nil {
+		return err
+	}
+
+	job, err := c.DoJobSubmitContext(ctx, &client.JobSubmission{
+		Name:        name,
+		AlgorithmId: algorithmId,
+		SceneId:     sceneId,
+		PlanetKey:   fields["planet_key"],
+		ComputeMask: computeMask,
+	})
+	if err != nil {
+		return err
+	}
+
+	return renderOne(job.String(), jobRecord(job))
 }
 
-func runJobSubmit() error {
-	return cli.NewExitError("job: --submit not yet supported", 2)
+func runJobDelete(ids []string, yes bool) error {
+	c, err := newJobClient()
+	if err != nil {
+		return err
+	}
+
+	if len(ids) > 1 && !yes {
+		if !confirm(fmt.Sprintf("Delete %d jobs?", len(ids))) {
+			return cli.NewExitError("job deletion: cancelled", 1)
+		}
+	}
+
+	return doJobDelete(c, ids)
 }
 
-func runJobDelete(id string) error {
-	return cli.NewExitError("job: --delete not yet supported", 2)
+func runJobDeleteByFilter(filter *client.JobFilter, yes bool) error {
+	c, err := newJobClient()
+	if err != nil {
+		return err
+	}
+
+	jobs, err := c.FindJobsContext(ctx, filter)
+	if err != nil {
+		return err
+	}
+	if len(jobs) == 0 {
+		return renderList("No matching jobs\n", deletedColumns, []record{})
+	}
+
+	// the jobs to be deleted are shown with the prompt, on stderr, so that
+	// stdout has only the one list of those deleted
+	ids := make([]string, len(jobs))
+	for i, job := range jobs {
+		ids[i] = job.Id
+		fmt.Fprintf(os.Stderr, "%s  %s  %s  %s\n", job.Id, job.Properties.Status, job.Properties.CreatedOn, job.Properties.Name)
+	}
+
+	if !yes {
+		if !confirm(fmt.Sprintf("Delete these %d jobs?", len(ids))) {
+			return cli.NewExitError("job deletion: cancelled", 1)
+		}
+	}
+
+	return doJobDelete(c, ids)
+}
+
+func doJobDelete(c *client.JobClient, ids []string) error {
+	// on partial failure, still report the jobs that were deleted
+	deleted, err := c.DoJobDeleteManyContext(ctx, ids)
+
+	text := ""
+	records := make([]record, len(deleted))
+	for i, id := range deleted {
+		text += fmt.Sprintf("Deleted %s\n", id)
+		records[i] = deletedRecord(id)
+	}
+	rerr := renderList(text, deletedColumns, records)
+	if err != nil {
+		return err
+	}
+	return rerr
 }
 
-func runCoastlineDownload(id string) error {
+// exit codes for job --wait
+const (
+	exitJobFailed      = 3
+	exitJobWaitTimeout = 4
+)
+
+// waits for the job, then downloads its coastline if download is set
+func runJobWait(id string, opts *client.WaitOptions, download *client.DownloadOptions) error {
+	c, err := newJobClient()
+	if err != nil {
+		return err
+	}
+
+	// text is written as the status changes; the other formats are one
+	// document, so are written once the wait is over, unless the download
+	// is to be that document, when the changes go to stderr as text
+	transitions := []record{}
+	opts.OnStatus = func(job *client.Job) {
+		now := time.Now()
+		text := fmt.Sprintf("%s  %s  %s\n", now.Format("15:04:05"), job.Id, job.Properties.Status)
+		switch {
+		case outputFormat == formatText:
+			fmt.Print(text)
+		case download != nil:
+			fmt.Fprint(os.Stderr, text)
+		default:
+			transitions = append(transitions, statusRecord(now, job))
+		}
+	}
+
+	job, err := c.WaitForJobContext(ctx, id, opts)
+	if outputFormat != formatText && download == nil {
+		rerr := renderList("", statusColumns, transitions)
+		if rerr != nil && err == nil {
+			return rerr
+		}
+	}
+	if err == client.ErrWaitTimeout {
+		return cli.NewExitError("job wait: "+err.Error(), exitJobWaitTimeout)
+	}
+	if err != nil {
+		return err
+	}
+
+	if job.Properties.Status != client.JobStatusSuccess {
+		msg := fmt.Sprintf("job wait: job finished with status %s", job.Properties.Status)
+		if job.Properties.ErrorMessage != "" {
+			msg += ": " + job.Properties.ErrorMessage
+		}
+		return cli.NewExitError(msg, exitJobFailed)
+	}
+
+	if download != nil {
+		return runCoastlineDownload(id, download)
+	}
+
+	return nil
+}
+
+func runCoastlineDownload(id string, opts *client.DownloadOptions) error {
 	c, err := newCoastlineClient()
 	if err != nil {
 		return err
 	}
-	s, err := c.DoDownload(id)
+	result, err := c.DoDownloadContext(ctx, id, opts)
+	if err != nil {
+		return err
+	}
+	return renderOne(result.String()+"\n", downloadRecord(result))
+}
+
+func runConfigInit() error {
+	rc, err := client.LoadBeachfrontrc()
+	if err != nil {
+		return err
+	}
+
+	name, err := ask("profile", rc.ProfileName())
+	if err != nil {
+		return err
+	}
+	client.SelectProfile(name)
+
+	if _, ok := rc.Profiles[name]; ok {
+		if !confirm(fmt.Sprintf("Replace profile '%s'?", name)) {
+			return cli.NewExitError("config init: cancelled", 1)
+		}
+	}
+
+	for _, field := range client.Fields {
+		def := rc.Get(field)
+		if client.SecretFields[field] && def != "" {
+			// keep the secret off the screen: empty means unchanged
+			value, err := ask(field+" (empty to keep "+client.Redact(def)+")", "")
+			if err != nil {
+				return err
+			}
+			if value != "" {
+				rc.Set(field, value)
+			}
+			continue
+		}
+		value, err := ask(field, def)
+		if err != nil {
+			return err
+		}
+		rc.Set(field, value)
+	}
+
+	if len(rc.Profiles) == 1 || rc.DefaultProfile == "" {
+		rc.DefaultProfile = name
+	}
+
+	err = rc.Save()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Wrote profile '%s' to %s\n", name, client.BeachfrontrcPath())
+
+	return reportProblems("", rc.Validate())
+}
+
+func runConfigShow() error {
+	settings, err := client.LoadSettings()
 	if err != nil {
 		return err
 	}
-	fmt.Print(s)
+
+	r := record{{"profile", settings.Profile}}
+	text := fmt.Sprintf("profile: %s\n", settings.Profile)
+	for _, name := range client.AllFields() {
+		value, source := "", ""
+		if setting := settings.Lookup(name); setting != nil {
+			value, source = setting.Value, setting.Origin
+			if client.SecretFields[name] {
+				value = client.Redact(value)
+			}
+		}
+		r = append(r, field{name, value}, field{name + "_source", source})
+		if source == "" {
+			text += fmt.Sprintf("%s: (not set)\n", name)
+		} else {
+			text += fmt.Sprintf("%s: %s  (%s)\n", name, value, source)
+		}
+	}
+
+	return renderOne(text, r)
+}
+
+func runConfigGet(name string) error {
+	settings, err := client.LoadSettings()
+	if err != nil {
+		return err
+	}
+	value := settings.Get(name)
+	if value == "" {
+		return cli.NewExitError(fmt.Sprintf("config get: '%s' is not set (profile '%s')", name, settings.Profile), 1)
+	}
+	return renderOne(value+"\n", record{{"name", name}, {"value", value}})
+}
+
+func runConfigSet(name string, value string) error {
+	if !isConfigField(name) {
+		return cli.NewExitError(fmt.Sprintf("config set: unknown field '%s' (fields: %s)", name, strings.Join(client.AllFields(), ", ")), 2)
+	}
+
+	rc, err := client.LoadBeachfrontrc()
+	if err != nil {
+		return err
+	}
+	rc.Set(name, value)
+	if rc.DefaultProfile == "" {
+		rc.DefaultProfile = rc.ProfileName()
+	}
+	return rc.Save()
+}
+
+func runConfigValidate() error {
+	settings, err := client.LoadSettings()
+	if err != nil {
+		return err
+	}
+	return reportProblems(fmt.Sprintf("configuration is valid (profile '%s')\n", settings.Profile), settings.Validate())
+}
+
+// renders the problems, or the text if there are none, failing if there
+// are any
+func reportProblems(text string, problems []error) error {
+	records := make([]record, len(problems))
+	if len(problems) > 0 {
+		text = ""
+	}
+	for i, problem := range problems {
+		text += problem.Error() + "\n"
+		records[i] = record{{"problem", problem.Error()}}
+	}
+	err := renderList(text, problemColumns, records)
+	if err != nil {
+		return err
+	}
+
+	if len(problems) > 0 {
+		return cli.NewExitError(fmt.Sprintf("config: %d problems found", len(problems)), 1)
+	}
 	return nil
 }
 
+func isConfigField(name string) bool {
+	for _, f := range client.AllFields() {
+		if f == name {
+			return true
+		}
+	}
+	return false
+}
+
 func runAlgorithmInfoForAll() error {
 	c, err := newAlgorithmClient()
 	if err != nil {
 		return err
 	}
-	s, err := c.GetInfoForAll()
+	algs, err := c.GetInfoForAllContext(ctx)
 	if err != nil {
 		return err
 	}
-	fmt.Print(s)
-	return nil
+
+	records := make([]record, len(algs.Algorithms))
+	for i, alg := range algs.Algorithms {
+		records[i] = algorithmRecord(alg)
+	}
+	return renderList(algs.String(), algorithmColumns, records)
 }
 
 func runAlgorithmInfoForOne(id string) error {
@@ -379,10 +1203,252 @@ func runAlgorithmInfoForOne(id string) error {
 	if err != nil {
 		return err
 	}
-	s, err := c.GetInfoForOne(id)
+	alg, err := c.GetInfoForOneContext(ctx, id)
 	if err != nil {
 		return err
 	}
-	fmt.Print(s)
-	return nil
+	return renderOne(alg.String()+"\n", algorithmRecord(alg))
+}
+
+//---------------------------------------------------------------------
+
+// output formats for --format
+const (
+	formatText  = "text"
+	formatJSON  = "json"
+	formatYAML  = "yaml"
+	formatCSV   = "csv"
+	formatTable = "table"
+)
+
+var outputFormat = formatText
+
+// a named value in an output record
+type field struct {
+	name  string
+	value interface{}
+}
+
+// record is one output object. Its fields keep their order in every format,
+// and their names are the stable, documented output names.
+type record []field
+
+func (r record) MarshalJSON() ([]byte, error) {
+	buf := &bytes.Buffer{}
+	buf.WriteString("{")
+	for i, f := range r {
+		if i > 0 {
+			buf.WriteString(",")
+		}
+		key, err := json.Marshal(f.name)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(f.value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteString(":")
+		buf.Write(value)
+	}
+	buf.WriteString("}")
+	return buf.Bytes(), nil
+}
+
+func (r record) MarshalYAML() (interface{}, error) {
+	m := yaml.MapSlice{}
+	for _, f := range r {
+		m = append(m, yaml.MapItem{Key: f.name, Value: f.value})
+	}
+	return m, nil
+}
+
+// renderList writes the records in the chosen format, or the text as is
+// for the text format. The columns head CSV and tables even when there are
+// no records.
+func renderList(text string, columns []string, records []record) error {
+	return render(os.Stdout, outputFormat, text, columns, records, false)
+}
+
+// renderOne is renderList for a single object: JSON and YAML get an object
+// instead of a list
+func renderOne(text string, r record) error {
+	return render(os.Stdout, outputFormat, text, r.names(), []record{r}, true)
+}
+
+func render(w io.Writer, format string, text string, columns []string, records []record, single bool) error {
+	switch format {
+	case formatText:
+		_, err := fmt.Fprint(w, text)
+		return err
+
+	case formatJSON:
+		var v interface{} = records
+		if single {
+			v = records[0]
+		}
+		byts, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w, string(byts))
+		return err
+
+	case formatYAML:
+		var v interface{} = records
+		if single {
+			v = records[0]
+		}
+		byts, err := yaml.Marshal(v)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(byts)
+		return err
+
+	case formatCSV:
+		out := csv.NewWriter(w)
+		out.Write(columns)
+		for _, r := range records {
+			out.Write(r.cells())
+		}
+		out.Flush()
+		return out.Error()
+
+	case formatTable:
+		out := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(out, strings.ToUpper(strings.Join(columns, "\t")))
+		for _, r := range records {
+			fmt.Fprintln(out, strings.Join(r.cells(), "\t"))
+		}
+		return out.Flush()
+	}
+
+	return fmt.Errorf("unknown output format: '%s'", format)
+}
+
+func (r record) names() []string {
+	names := make([]string, len(r))
+	for i, f := range r {
+		names[i] = f.name
+	}
+	return names
+}
+
+// flattens the values for CSV and tables
+func (r record) cells() []string {
+	cells := make([]string, len(r))
+	for i, f := range r {
+		switch v := f.value.(type) {
+		case []float64:
+			parts := make([]string, len(v))
+			for j, x := range v {
+				parts[j] = strconv.FormatFloat(x, 'f', -1, 64)
+			}
+			cells[i] = strings.Join(parts, ",")
+		case map[string]string:
+			keys := make([]string, 0, len(v))
+			for k := range v {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			cells[i] = strings.Join(keys, " ")
+		default:
+			cells[i] = fmt.Sprint(v)
+		}
+	}
+	return cells
+}
+
+//---------------------------------------------------------------------
+
+// the names of each kind of record, for the header of a list
+var (
+	algorithmColumns = algorithmRecord(&client.AlgorithmInfo{}).names()
+	catalogColumns   = catalogFeatureRecord(&client.CatalogFeature{}).names()
+	jobColumns       = jobRecord(&client.Job{Properties: &client.JobProperties{}}).names()
+	statusColumns    = statusRecord(time.Time{}, &client.Job{Properties: &client.JobProperties{}}).names()
+	deletedColumns   = deletedRecord("").names()
+	downloadColumns  = downloadRecord(&client.DownloadResult{}).names()
+	problemColumns   = []string{"problem"}
+)
+
+func algorithmRecord(a *client.AlgorithmInfo) record {
+	return record{
+		{"service_id", a.ServiceId},
+		{"name", a.Name},
+		{"version", a.Version},
+		{"interface", a.Interface},
+		{"max_cloud_cover", a.MaxCloudCover},
+		{"description", a.Description},
+	}
+}
+
+func catalogRecords(c *client.Catalog) []record {
+	records := make([]record, len(c.Features))
+	for i, f := range c.Features {
+		records[i] = catalogFeatureRecord(f)
+	}
+	return records
+}
+
+func catalogFeatureRecord(f *client.CatalogFeature) record {
+	p := f.Properties
+	if p == nil {
+		p = &client.PropertiesInfo{}
+	}
+	bands := p.Bands
+	if bands == nil {
+		bands = map[string]string{}
+	}
+	return record{
+		{"id", f.Id},
+		{"acquired_date", p.AcquiredDate},
+		{"cloud_cover", p.CloudCover},
+		{"sensor_name", p.SensorName},
+		{"resolution", p.Resolution},
+		{"file_format", p.FileFormat},
+		{"bbox", f.Bbox[:]},
+		{"bands", bands},
+	}
+}
+
+func jobRecord(j *client.Job) record {
+	p := j.Properties
+	return record{
+		{"id", j.Id},
+		{"name", p.Name},
+		{"status", p.Status},
+		{"created_on", p.CreatedOn},
+		{"algorithm_name", p.AlgorithmName},
+		{"algorithm_version", p.AlgorithmVersion},
+		{"scene_id", p.SceneId},
+		{"error_message", p.ErrorMessage},
+	}
+}
+
+// a job's status as seen at a time while waiting for it
+func statusRecord(t time.Time, j *client.Job) record {
+	return record{
+		{"time", t.Format(time.RFC3339)},
+		{"id", j.Id},
+		{"status", j.Properties.Status},
+	}
+}
+
+func deletedRecord(id string) record {
+	return record{
+		{"id", id},
+		{"deleted", true},
+	}
+}
+
+func downloadRecord(r *client.DownloadResult) record {
+	return record{
+		{"file", r.File},
+		{"band", r.Band},
+		{"bytes", r.Bytes},
+		{"skipped", r.Skipped},
+	}
 }
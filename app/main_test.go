@@ -0,0 +1,126 @@
+/* Copyright 2017, RadiantBlue Technologies, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRender(t *testing.T) {
+	columns := []string{"id", "bbox", "bands", "ok"}
+	records := []record{
+		{{"id", "b"}, {"bbox", []float64{1, 2.5}}, {"bands", map[string]string{"red": "r", "blue": "b"}}, {"ok", true}},
+		{{"id", "a"}, {"bbox", []float64{}}, {"bands", map[string]string{}}, {"ok", false}},
+	}
+
+	tests := []struct {
+		format  string
+		records []record
+		single  bool
+		out     string
+	}{
+		{formatText, records, false, "some text\n"},
+		{formatJSON, records, false, `[
+  {
+    "id": "b",
+    "bbox": [
+      1,
+      2.5
+    ],
+    "bands": {
+      "blue": "b",
+      "red": "r"
+    },
+    "ok": true
+  },
+  {
+    "id": "a",
+    "bbox": [],
+    "bands": {},
+    "ok": false
+  }
+]
+`},
+		{formatJSON, records[1:], true, `{
+  "id": "a",
+  "bbox": [],
+  "bands": {},
+  "ok": false
+}
+`},
+		{formatJSON, []record{}, false, "[]\n"},
+		{formatYAML, records[:1], false, `- id: b
+  bbox:
+  - 1
+  - 2.5
+  bands:
+    blue: b
+    red: r
+  ok: true
+`},
+		{formatYAML, records[1:], true, "id: a\nbbox: []\nbands: {}\nok: false\n"},
+		{formatYAML, []record{}, false, "[]\n"},
+		{formatCSV, records, false, "id,bbox,bands,ok\nb,\"1,2.5\",blue red,true\na,,,false\n"},
+		{formatCSV, []record{}, false, "id,bbox,bands,ok\n"},
+		{formatTable, records, false, "ID  BBOX   BANDS     OK\nb   1,2.5  blue red  true\na                    false\n"},
+		{formatTable, []record{}, false, "ID  BBOX  BANDS  OK\n"},
+	}
+
+	for _, test := range tests {
+		buf := &bytes.Buffer{}
+		err := render(buf, test.format, "some text\n", columns, test.records, test.single)
+		assert.NoError(t, err, test.format)
+		assert.Equal(t, test.out, buf.String(), test.format)
+	}
+
+	err := render(&bytes.Buffer{}, "xml", "", columns, records, false)
+	assert.EqualError(t, err, "unknown output format: 'xml'")
+}
+
+func TestRecordCells(t *testing.T) {
+	tests := []struct {
+		value interface{}
+		cell  string
+	}{
+		{"text", "text"},
+		{12.5, "12.5"},
+		{int64(1024), "1024"},
+		{true, "true"},
+		{[]float64{-122.5, 37, 0.125}, "-122.5,37,0.125"},
+		{map[string]string{"nir": "x", "blue": "y", "red": "z"}, "blue nir red"},
+	}
+
+	for _, test := range tests {
+		r := record{{"name", test.value}}
+		assert.Equal(t, []string{"name"}, r.names())
+		assert.Equal(t, []string{test.cell}, r.cells())
+	}
+}
+
+func TestRecordColumns(t *testing.T) {
+	assert := assert.New(t)
+
+	// the names of an empty list match those of its records
+	assert.Equal([]string{"time", "id", "status"}, statusColumns)
+	assert.Equal([]string{"id", "deleted"}, deletedColumns)
+	assert.Equal([]string{"file", "band", "bytes", "skipped"}, downloadColumns)
+	assert.Equal("id", jobColumns[0])
+	assert.Equal("bands", catalogColumns[len(catalogColumns)-1])
+	assert.Equal("service_id", algorithmColumns[0])
+}
@@ -0,0 +1,136 @@
+/* Copyright 2017, RadiantBlue Technologies, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/venicegeo/bf-client/client"
+)
+
+// how often the non-terminal display logs a file still downloading
+const progressLogInterval = 5 * time.Second
+
+// newProgressDisplay returns a live, redrawn display when stdout is a
+// terminal, and periodic log lines otherwise, as in a pipe or CI. Both
+// write to stderr, keeping stdout for the command's output.
+func newProgressDisplay() client.ProgressReporter {
+	return progressDisplayFor(isTerminal(os.Stdout), os.Stderr)
+}
+
+func progressDisplayFor(terminal bool, out io.Writer) client.ProgressReporter {
+	if terminal {
+		return &liveProgress{out: out, index: map[string]int{}}
+	}
+	return &logProgress{last: map[string]time.Time{}}
+}
+
+func isTerminal(f *os.File) bool {
+	stat, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return stat.Mode()&os.ModeCharDevice != 0
+}
+
+//---------------------------------------------------------------------
+
+// one line per file, redrawn in place
+type liveProgress struct {
+	out   io.Writer
+	lines []*client.Progress
+	index map[string]int // file -> line
+	drawn int            // lines on screen from the last redraw
+	mutex sync.Mutex
+}
+
+func (d *liveProgress) Report(p *client.Progress) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	i, ok := d.index[p.File]
+	if !ok {
+		i = len(d.lines)
+		d.index[p.File] = i
+		d.lines = append(d.lines, p)
+	}
+	d.lines[i] = p
+
+	if d.drawn > 0 {
+		fmt.Fprintf(d.out, "\033[%dA", d.drawn)
+	}
+	for _, line := range d.lines {
+		fmt.Fprintf(d.out, "\r\033[K%s\n", formatProgress(line))
+	}
+	d.drawn = len(d.lines)
+}
+
+//---------------------------------------------------------------------
+
+// log lines, at most one per file per progressLogInterval, plus the finish
+type logProgress struct {
+	last  map[string]time.Time
+	mutex sync.Mutex
+}
+
+func (d *logProgress) Report(p *client.Progress) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	now := time.Now()
+	last, ok := d.last[p.File]
+	if ok && !p.Finished && now.Sub(last) < progressLogInterval {
+		return
+	}
+	d.last[p.File] = now
+
+	log.Print(formatProgress(p))
+}
+
+//---------------------------------------------------------------------
+
+// "LC81260322017212LGN00_B4.TIF   45%   27.1 MB / 60.2 MB   3.2 MB/s"
+func formatProgress(p *client.Progress) string {
+	switch {
+	case p.Err != nil:
+		return fmt.Sprintf("%-32s failed: %s", p.File, p.Err.Error())
+	case p.Finished:
+		return fmt.Sprintf("%-32s done   %s", p.File, formatBytes(p.Done))
+	case p.Total > 0:
+		return fmt.Sprintf("%-32s %3d%%   %s / %s   %s/s", p.File, p.Done*100/p.Total,
+			formatBytes(p.Done), formatBytes(p.Total), formatBytes(int64(p.Rate)))
+	default:
+		return fmt.Sprintf("%-32s %s   %s/s", p.File, formatBytes(p.Done), formatBytes(int64(p.Rate)))
+	}
+}
+
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "KMGTPE"[exp])
+}
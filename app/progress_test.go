@@ -0,0 +1,72 @@
+/* Copyright 2017, RadiantBlue Technologies, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/venicegeo/bf-client/client"
+)
+
+func TestProgressDisplay(t *testing.T) {
+	assert := assert.New(t)
+
+	logged := &bytes.Buffer{}
+	log.SetOutput(logged)
+	defer log.SetOutput(os.Stderr)
+
+	// a terminal gets the live display, on the writer given
+	out := &bytes.Buffer{}
+	live := progressDisplayFor(true, out)
+	assert.IsType(&liveProgress{}, live)
+	live.Report(&client.Progress{File: "a.TIF", Done: 512, Total: 1024})
+	live.Report(&client.Progress{File: "a.TIF", Done: 1024, Total: 1024, Finished: true})
+	assert.Contains(out.String(), "a.TIF")
+	assert.Contains(out.String(), "\033[1A")
+	assert.Empty(logged.String())
+
+	// anything else gets log lines, whatever the log level: the start, and
+	// the finish
+	out.Reset()
+	logs := progressDisplayFor(false, out)
+	assert.IsType(&logProgress{}, logs)
+	logs.Report(&client.Progress{File: "b.TIF", Done: 1, Total: 1024})
+	logs.Report(&client.Progress{File: "b.TIF", Done: 2, Total: 1024})
+	logs.Report(&client.Progress{File: "b.TIF", Done: 1024, Total: 1024, Finished: true})
+	assert.Empty(out.String())
+	assert.Equal(2, bytes.Count(logged.Bytes(), []byte("b.TIF")))
+	assert.Contains(logged.String(), "done")
+}
+
+func TestFormatBytes(t *testing.T) {
+	tests := []struct {
+		n    int64
+		text string
+	}{
+		{0, "0 B"},
+		{1023, "1023 B"},
+		{1024, "1.0 KB"},
+		{27 * 1024 * 1024, "27.0 MB"},
+		{3 << 30, "3.0 GB"},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.text, formatBytes(test.n))
+	}
+}
@@ -0,0 +1,359 @@
+/* Copyright 2017, RadiantBlue Technologies, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bftest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Job is a job in the fake bf-api's job list.
+type Job struct {
+	Id               string // made up if empty
+	Name             string
+	Status           string // "Submitted" if empty
+	CreatedBy        string
+	CreatedOn        time.Time // now if zero
+	AlgorithmName    string
+	AlgorithmVersion string
+	SceneId          string // "<catalog>:<scene>"
+	ErrorMessage     string
+	ExecutionStep    string
+
+	// Then holds the statuses the job moves through: each time the job is
+	// fetched on its own, it is reported and then takes on the next one.
+	Then []string
+}
+
+// Algorithm is an algorithm the fake bf-api offers.
+type Algorithm struct {
+	ServiceId     string
+	Name          string
+	Description   string
+	Interface     string
+	MaxCloudCover int
+	Version       string
+}
+
+// AddJob adds the job to the job list, or replaces the one with its id,
+// and returns the id.
+func (s *Server) AddJob(job *Job) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.addJob(job)
+}
+
+// the caller holds the lock
+func (s *Server) addJob(job *Job) string {
+	j := *job
+	if j.Id == "" {
+		s.nextJob++
+		j.Id = fmt.Sprintf("00000000-0000-4000-8000-%012d", s.nextJob)
+	}
+	if j.Status == "" {
+		j.Status = "Submitted"
+	}
+	if j.CreatedOn.IsZero() {
+		j.CreatedOn = time.Now().UTC()
+	}
+	j.Then = append([]string{}, job.Then...)
+
+	if _, ok := s.jobs[j.Id]; !ok {
+		s.jobOrder = append(s.jobOrder, j.Id)
+	}
+	s.jobs[j.Id] = &j
+	return j.Id
+}
+
+// Job returns a copy of the job as it now is, or nil if there is no such
+// job.
+func (s *Server) Job(id string) *Job {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job, ok := s.jobs[id]
+	if !ok {
+		return nil
+	}
+	j := *job
+	j.Then = append([]string{}, job.Then...)
+	return &j
+}
+
+// SetJobStatus changes the status of a job, dropping any statuses it was
+// going to move through.
+func (s *Server) SetJobStatus(id string, status string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if job, ok := s.jobs[id]; ok {
+		job.Status = status
+		job.Then = nil
+	}
+}
+
+// AddAlgorithm adds the algorithm to those offered.
+func (s *Server) AddAlgorithm(alg *Algorithm) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	a := *alg
+	s.algorithms = append(s.algorithms, &a)
+}
+
+// SetCoastline sets the GeoJSON served as the coastline of a job.
+func (s *Server) SetCoastline(id string, geojson []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.coastlines[id] = geojson
+}
+
+//---------------------------------------------------------------------
+
+// the bf-api's wire form of a job
+type jobFeature struct {
+	Type       string        `json:"type"`
+	Id         string        `json:"id"`
+	Properties jobProperties `json:"properties"`
+}
+
+type jobProperties struct {
+	Name             string `json:"name"`
+	Status           string `json:"status"`
+	CreatedBy        string `json:"created_by"`
+	CreatedOn        string `json:"created_on"`
+	AlgorithmName    string `json:"algorithm_name"`
+	AlgorithmVersion string `json:"algorithm_version"`
+	SceneId          string `json:"scene_id"`
+	ErrorMessage     string `json:"error_message,omitempty"`
+	ExecutionStep    string `json:"execution_step,omitempty"`
+}
+
+func (j *Job) feature() *jobFeature {
+	return &jobFeature{
+		Type: "Feature",
+		Id:   j.Id,
+		Properties: jobProperties{
+			Name:             j.Name,
+			Status:           j.Status,
+			CreatedBy:        j.CreatedBy,
+			CreatedOn:        j.CreatedOn.Format(time.RFC3339Nano),
+			AlgorithmName:    j.AlgorithmName,
+			AlgorithmVersion: j.AlgorithmVersion,
+			SceneId:          j.SceneId,
+			ErrorMessage:     j.ErrorMessage,
+			ExecutionStep:    j.ExecutionStep,
+		},
+	}
+}
+
+// what a job is submitted with
+type jobSubmission struct {
+	Name        string `json:"name"`
+	AlgorithmId string `json:"algorithm_id"`
+	SceneId     string `json:"scene_id"`
+	PlanetKey   string `json:"planet_api_key"`
+}
+
+// the wire form of an algorithm
+type algorithmInfo struct {
+	Description   string `json:"description"`
+	Interface     string `json:"interface"`
+	MaxCloudCover int    `json:"max_cloud_cover"`
+	Name          string `json:"name"`
+	ServiceId     string `json:"service_id"`
+	Version       string `json:"version"`
+}
+
+func (a *Algorithm) info() *algorithmInfo {
+	return &algorithmInfo{
+		Description:   a.Description,
+		Interface:     a.Interface,
+		MaxCloudCover: a.MaxCloudCover,
+		Name:          a.Name,
+		ServiceId:     a.ServiceId,
+		Version:       a.Version,
+	}
+}
+
+// /v0/job and /v0/job/<id>
+func (s *Server) serveJob(w http.ResponseWriter, r *http.Request, id string) {
+	switch {
+	case id == "" && r.Method == "GET":
+		s.mu.Lock()
+		features := []*jobFeature{}
+		for _, id := range s.jobOrder {
+			features = append(features, s.jobs[id].feature())
+		}
+		s.mu.Unlock()
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"jobs": map[string]interface{}{"type": "FeatureCollection", "features": features},
+		})
+
+	case id == "" && r.Method == "POST":
+		s.submitJob(w, r)
+
+	case id != "" && r.Method == "GET":
+		s.mu.Lock()
+		job, ok := s.jobs[id]
+		var feature *jobFeature
+		if ok {
+			feature = job.feature()
+			if len(job.Then) > 0 {
+				job.Status, job.Then = job.Then[0], job.Then[1:]
+			}
+		}
+		s.mu.Unlock()
+		if !ok {
+			writeError(w, http.StatusNotFound, "job not found: "+id)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"job": feature})
+
+	case id != "" && r.Method == "DELETE":
+		s.mu.Lock()
+		_, ok := s.jobs[id]
+		if ok {
+			delete(s.jobs, id)
+			for i, v := range s.jobOrder {
+				if v == id {
+					s.jobOrder = append(s.jobOrder[:i], s.jobOrder[i+1:]...)
+					break
+				}
+			}
+		}
+		s.mu.Unlock()
+		if !ok {
+			writeError(w, http.StatusNotFound, "job not found: "+id)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+
+	default:
+		writeError(w, http.StatusMethodNotAllowed, r.Method+" not allowed")
+	}
+}
+
+// checks a submission as the bf-api would, against the algorithms and
+// scenes the server has
+func (s *Server) submitJob(w http.ResponseWriter, r *http.Request) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	sub := &jobSubmission{}
+	if err = json.Unmarshal(body, sub); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid job submission: "+err.Error())
+		return
+	}
+	if sub.PlanetKey != PlanetKey {
+		writeError(w, http.StatusUnauthorized, "missing or wrong Planet key")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var alg *Algorithm
+	for _, a := range s.algorithms {
+		if a.ServiceId == sub.AlgorithmId {
+			alg = a
+		}
+	}
+	if alg == nil {
+		writeError(w, http.StatusBadRequest, "no such algorithm: "+sub.AlgorithmId)
+		return
+	}
+	parts := strings.SplitN(sub.SceneId, ":", 2)
+	if len(parts) != 2 || s.scene(parts[0], parts[1]) == nil {
+		writeError(w, http.StatusBadRequest, "no such scene: "+sub.SceneId)
+		return
+	}
+
+	id := s.addJob(&Job{
+		Name:             sub.Name,
+		CreatedBy:        Auth,
+		AlgorithmName:    alg.Name,
+		AlgorithmVersion: alg.Version,
+		SceneId:          sub.SceneId,
+	})
+	writeJSON(w, http.StatusCreated, map[string]interface{}{"job": s.jobs[id].feature()})
+}
+
+// /v0/algorithm and /v0/algorithm/<id>
+func (s *Server) serveAlgorithm(w http.ResponseWriter, r *http.Request, id string) {
+	if r.Method != "GET" {
+		writeError(w, http.StatusMethodNotAllowed, r.Method+" not allowed")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if id == "" {
+		infos := []*algorithmInfo{}
+		for _, a := range s.algorithms {
+			infos = append(infos, a.info())
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"algorithms": infos})
+		return
+	}
+
+	for _, a := range s.algorithms {
+		if a.ServiceId == id {
+			writeJSON(w, http.StatusOK, map[string]interface{}{"algorithm": a.info()})
+			return
+		}
+	}
+	writeError(w, http.StatusNotFound, "algorithm not found: "+id)
+}
+
+// /v0/job/<id>.geojson
+func (s *Server) serveCoastline(w http.ResponseWriter, r *http.Request, id string) {
+	s.mu.Lock()
+	geojson, ok := s.coastlines[id]
+	s.mu.Unlock()
+
+	if !ok {
+		writeError(w, http.StatusNotFound, "no coastline for job: "+id)
+		return
+	}
+	w.Header().Set("Content-Type", "application/geo+json")
+	w.Write(geojson)
+}
+
+//---------------------------------------------------------------------
+
+func writeJSON(w http.ResponseWriter, status int, obj interface{}) {
+	byts, err := json.Marshal(obj)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(byts)
+}
+
+// errors are written as the bf-api does, {"error": {"message": "..."}}
+func writeError(w http.ResponseWriter, status int, message string) {
+	byts, _ := json.Marshal(map[string]interface{}{"error": map[string]string{"message": message}})
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(byts)
+}
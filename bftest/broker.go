@@ -0,0 +1,352 @@
+/* Copyright 2017, RadiantBlue Technologies, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bftest
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Scene is a scene in one of the fake broker's catalogs.
+type Scene struct {
+	Catalog      string // e.g. "landsat"
+	Id           string
+	AcquiredDate time.Time
+	CloudCover   float64
+	FileFormat   string
+	Resolution   float64
+	SensorName   string
+	Bbox         [4]float64 // minx, miny, maxx, maxy; also the footprint
+
+	// Bands holds the contents of each band's file, by band name. The
+	// server serves them itself, with no key needed, as Planet links to
+	// files that are already signed.
+	Bands map[string][]byte
+}
+
+// where band files are served from
+const bandsPath = "/bands/"
+
+// AddScene adds the scene to its catalog, or replaces the one with its id.
+func (s *Server) AddScene(scene *Scene) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sc := *scene
+	scenes := s.scenes[sc.Catalog]
+	for i, v := range scenes {
+		if v.Id == sc.Id {
+			scenes[i] = &sc
+			return
+		}
+	}
+	s.scenes[sc.Catalog] = append(scenes, &sc)
+}
+
+// SetPageSize makes searches return at most n scenes per page, with a
+// link to the next page, as the broker does; 0 returns them all at once.
+func (s *Server) SetPageSize(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pageSize = n
+}
+
+// the caller holds the lock
+func (s *Server) scene(catalog string, id string) *Scene {
+	for _, v := range s.scenes[catalog] {
+		if v.Id == id {
+			return v
+		}
+	}
+	return nil
+}
+
+//---------------------------------------------------------------------
+
+// the broker's wire form of a scene
+type sceneFeature struct {
+	Type       string                 `json:"type"`
+	Id         string                 `json:"id"`
+	Geometry   map[string]interface{} `json:"geometry"`
+	Bbox       [4]float64             `json:"bbox"`
+	Properties sceneProperties        `json:"properties"`
+}
+
+type sceneProperties struct {
+	AcquiredDate string            `json:"acquiredDate"`
+	Bands        map[string]string `json:"bands"`
+	CloudCover   float64           `json:"cloudCover"`
+	FileFormat   string            `json:"fileFormat"`
+	Resolution   float64           `json:"resolution"`
+	SensorName   string            `json:"sensorName"`
+}
+
+// the feature for a scene, with band links on the given server
+func (sc *Scene) feature(base string) *sceneFeature {
+	b := sc.Bbox
+	f := &sceneFeature{
+		Type: "Feature",
+		Id:   sc.Id,
+		Geometry: map[string]interface{}{
+			"type":        "Polygon",
+			"coordinates": [][][2]float64{{{b[0], b[1]}, {b[2], b[1]}, {b[2], b[3]}, {b[0], b[3]}, {b[0], b[1]}}},
+		},
+		Bbox: b,
+		Properties: sceneProperties{
+			AcquiredDate: sc.AcquiredDate.UTC().Format(time.RFC3339Nano),
+			Bands:        map[string]string{},
+			CloudCover:   sc.CloudCover,
+			FileFormat:   sc.FileFormat,
+			Resolution:   sc.Resolution,
+			SensorName:   sc.SensorName,
+		},
+	}
+	for name := range sc.Bands {
+		f.Properties.Bands[name] = fmt.Sprintf("%s%s%s/%s/%s", base, bandsPath, sc.Catalog, sc.Id, sc.bandFile(name))
+	}
+	return f
+}
+
+// the file a band is served as, "<scene>_<band>.TIF" as Landsat names them
+func (sc *Scene) bandFile(band string) string {
+	return sc.Id + "_" + band + ".TIF"
+}
+
+func baseURL(r *http.Request) string {
+	return "http://" + r.Host
+}
+
+// /planet/discover/<catalog>, filtered by cloudCover, bbox, acquiredDate
+// and maxAcquiredDate, a page at a time
+func (s *Server) serveDiscover(w http.ResponseWriter, r *http.Request, catalog string) {
+	params := r.URL.Query()
+
+	maxCloudCover := 100.0
+	if v := params.Get("cloudCover"); v != "" {
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "bad cloudCover: "+v)
+			return
+		}
+		maxCloudCover = f
+	}
+
+	bbox, err := parseBbox(params.Get("bbox"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var after, before time.Time
+	for name, t := range map[string]*time.Time{"acquiredDate": &after, "maxAcquiredDate": &before} {
+		if v := params.Get(name); v != "" {
+			parsed, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, "bad "+name+": "+v)
+				return
+			}
+			*t = parsed
+		}
+	}
+
+	page := 1
+	if v := params.Get("page"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			writeError(w, http.StatusBadRequest, "bad page: "+v)
+			return
+		}
+		page = n
+	}
+
+	filter := &sceneFilter{maxCloudCover: maxCloudCover, bbox: bbox, after: after, before: before}
+
+	s.mu.Lock()
+	_, ok := s.scenes[catalog]
+	matches := s.findScenes([]string{catalog}, filter)
+	pageSize := s.pageSize
+	s.mu.Unlock()
+
+	if !ok {
+		writeError(w, http.StatusNotFound, "catalog not found: "+catalog)
+		return
+	}
+
+	next := ""
+	matches, more := pageOf(matches, page, pageSize)
+	if more {
+		params.Set("page", strconv.Itoa(page+1))
+		next = (&url.URL{Path: r.URL.Path, RawQuery: params.Encode()}).String()
+	}
+
+	features := []*sceneFeature{}
+	for _, sc := range matches {
+		features = append(features, sc.feature(baseURL(r)))
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"type":     "FeatureCollection",
+		"features": features,
+		"_links":   map[string]string{"_next": next},
+	})
+}
+
+// what a search matches; but for maxCloudCover, zero values match
+// everything
+type sceneFilter struct {
+	maxCloudCover float64 // percent; 100 for any
+	bbox          []float64
+	after         time.Time
+	before        time.Time
+}
+
+func (f *sceneFilter) matches(sc *Scene) bool {
+	bbox := f.bbox
+	switch {
+	case sc.CloudCover > f.maxCloudCover:
+	case bbox != nil && !bboxesOverlap(sc.Bbox[:], bbox):
+	case !f.after.IsZero() && sc.AcquiredDate.Before(f.after):
+	case !f.before.IsZero() && sc.AcquiredDate.After(f.before):
+	default:
+		return true
+	}
+	return false
+}
+
+// whether two bboxes overlap. Either may cross the antimeridian, as in
+// GeoJSON, its minx then being greater than its maxx.
+func bboxesOverlap(a []float64, b []float64) bool {
+	if a[1] > b[3] || a[3] < b[1] {
+		return false
+	}
+	for _, x := range lonRanges(a[0], a[2]) {
+		for _, y := range lonRanges(b[0], b[2]) {
+			if x[0] <= y[1] && y[0] <= x[1] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// the longitudes from min to max, in two ranges if they cross 180°
+func lonRanges(min float64, max float64) [][2]float64 {
+	if min <= max {
+		return [][2]float64{{min, max}}
+	}
+	return [][2]float64{{min, 180}, {-180, max}}
+}
+
+// the scenes of the catalogs that match the filter, newest first; the
+// caller holds the lock
+func (s *Server) findScenes(catalogs []string, filter *sceneFilter) []*Scene {
+	matches := []*Scene{}
+	for _, catalog := range catalogs {
+		for _, sc := range s.scenes[catalog] {
+			if filter.matches(sc) {
+				matches = append(matches, sc)
+			}
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].AcquiredDate.After(matches[j].AcquiredDate)
+	})
+	return matches
+}
+
+// the page'th page of the scenes, counting from 1, and whether there are
+// more after it; a size of 0 puts them all on one page
+func pageOf(scenes []*Scene, page int, size int) ([]*Scene, bool) {
+	if size <= 0 {
+		return scenes, false
+	}
+	start := (page - 1) * size
+	if start > len(scenes) {
+		start = len(scenes)
+	}
+	end := start + size
+	if end >= len(scenes) {
+		return scenes[start:], false
+	}
+	return scenes[start:end], true
+}
+
+// "minx,miny,maxx,maxy", or nil if empty
+func parseBbox(v string) ([]float64, error) {
+	if v == "" {
+		return nil, nil
+	}
+	var bbox []float64
+	for _, part := range strings.Split(v, ",") {
+		f, err := strconv.ParseFloat(part, 64)
+		if err != nil {
+			return nil, fmt.Errorf("bad bbox: %s", v)
+		}
+		bbox = append(bbox, f)
+	}
+	if len(bbox) != 4 {
+		return nil, fmt.Errorf("bad bbox: %s", v)
+	}
+	return bbox, nil
+}
+
+// /planet/<catalog>/<scene>
+func (s *Server) serveScene(w http.ResponseWriter, r *http.Request, id string) {
+	parts := strings.SplitN(id, "/", 2)
+	if len(parts) != 2 {
+		writeError(w, http.StatusNotFound, "no such endpoint: "+r.URL.Path)
+		return
+	}
+
+	s.mu.Lock()
+	sc := s.scene(parts[0], parts[1])
+	s.mu.Unlock()
+
+	if sc == nil {
+		writeError(w, http.StatusNotFound, "scene not found: "+parts[0]+":"+parts[1])
+		return
+	}
+	writeJSON(w, http.StatusOK, sc.feature(baseURL(r)))
+}
+
+// /bands/<catalog>/<scene>/<file>, with range requests
+func (s *Server) serveBand(w http.ResponseWriter, r *http.Request, id string) {
+	parts := strings.SplitN(id, "/", 3)
+	var byts []byte
+	found := false
+	if len(parts) == 3 {
+		s.mu.Lock()
+		if sc := s.scene(parts[0], parts[1]); sc != nil {
+			for name, contents := range sc.Bands {
+				if sc.bandFile(name) == parts[2] {
+					byts, found = contents, true
+				}
+			}
+		}
+		s.mu.Unlock()
+	}
+
+	if !found {
+		http.NotFound(w, r)
+		return
+	}
+	http.ServeContent(w, r, parts[2], time.Time{}, bytes.NewReader(byts))
+}
@@ -0,0 +1,250 @@
+/* Copyright 2017, RadiantBlue Technologies, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package bftest runs a stand-in for the Beachfront services, the bf-api
+// and the image broker, and for a STAC API serving the same scenes, for
+// testing code that talks to them without a network or an account.
+//
+//	server := bftest.NewServer()
+//	defer server.Close()
+//	server.AddAlgorithm(&bftest.Algorithm{ServiceId: "a1", Name: "NDWI_PY"})
+//
+//	c, err := client.NewClientWithConfig(&client.Config{
+//		APIURL:    server.URL,
+//		BrokerURL: server.URL,
+//		Auth:      bftest.Auth,
+//		PlanetKey: bftest.PlanetKey,
+//	})
+//
+// The server starts empty: jobs, algorithms, coastlines and scenes are
+// added as fixtures, and failures and latency can be injected.
+package bftest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The keys the server accepts. A request without the right one is refused
+// with a 401, as the real services would.
+const (
+	Auth      = "bftest-api-key"    // the bf-api key, as the basic auth user
+	PlanetKey = "bftest-planet-key" // the Planet key, as the broker takes it
+)
+
+// Server is a running fake. Its methods may be called at any time, also
+// while requests are being served.
+type Server struct {
+	*httptest.Server // URL is the base URL of both services
+
+	mu         sync.Mutex
+	jobs       map[string]*Job
+	jobOrder   []string
+	nextJob    int
+	algorithms []*Algorithm
+	coastlines map[string][]byte
+	scenes     map[string][]*Scene // catalog -> scenes
+	pageSize   int
+	brokerAuth map[string]bool // how the broker takes the Planet key
+	latency    time.Duration
+	failures   []*failure
+	requests   []string
+}
+
+// a failure to inject into the requests matching a path prefix
+type failure struct {
+	prefix string
+	status int
+	count  int // left to fail, or < 0 for all
+}
+
+// NewServer starts a server with no fixtures. Close it when done.
+func NewServer() *Server {
+	s := &Server{
+		jobs:       map[string]*Job{},
+		coastlines: map[string][]byte{},
+		scenes:     map[string][]*Scene{},
+		brokerAuth: map[string]bool{"header": true, "basic": true, "query": true},
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	return s
+}
+
+// Settings returns the client settings for using the server, by field
+// name, as taken by client.SetOverrides.
+func (s *Server) Settings() map[string]string {
+	return map[string]string{
+		"api_url":    s.URL,
+		"broker_url": s.URL,
+		"stac_url":   s.STACURL(),
+		"auth":       Auth,
+		"planet_key": PlanetKey,
+	}
+}
+
+// SetLatency delays every response by d, or until the request is canceled.
+func (s *Server) SetLatency(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.latency = d
+}
+
+// SetBrokerAuth sets the ways the broker takes the Planet key: "header",
+// as "Authorization: api-key <key>", "basic", as the basic auth user name,
+// and "query", as the PL_API_KEY parameter. It takes all three until told
+// otherwise; SetBrokerAuth("query") stands in for a broker that takes
+// only the parameter.
+func (s *Server) SetBrokerAuth(ways ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.brokerAuth = map[string]bool{}
+	for _, way := range ways {
+		s.brokerAuth[way] = true
+	}
+}
+
+// Fail makes the next count requests whose path starts with prefix fail
+// with the status; a count below zero fails all of them. Failures are
+// matched in the order they were added.
+func (s *Server) Fail(prefix string, status int, count int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures = append(s.failures, &failure{prefix: prefix, status: status, count: count})
+}
+
+// ClearFailures removes any failures not yet used up.
+func (s *Server) ClearFailures() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures = nil
+}
+
+// Requests returns the requests served so far, oldest first, as
+// "METHOD /path", without any query.
+func (s *Server) Requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string{}, s.requests...)
+}
+
+//---------------------------------------------------------------------
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.requests = append(s.requests, r.Method+" "+r.URL.Path)
+	latency := s.latency
+	status := s.injectedFailure(r.URL.Path)
+	s.mu.Unlock()
+
+	if latency > 0 {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-time.After(latency):
+		}
+	}
+
+	if status != 0 {
+		writeError(w, status, "injected failure")
+		return
+	}
+
+	path := r.URL.Path
+	switch {
+	case path == "/v0/algorithm" || strings.HasPrefix(path, "/v0/algorithm/"):
+		if s.authorizeAPI(w, r) {
+			s.serveAlgorithm(w, r, strings.TrimPrefix(strings.TrimPrefix(path, "/v0/algorithm"), "/"))
+		}
+	case strings.HasPrefix(path, "/v0/job/") && strings.HasSuffix(path, ".geojson"):
+		if s.authorizeAPI(w, r) {
+			s.serveCoastline(w, r, strings.TrimSuffix(strings.TrimPrefix(path, "/v0/job/"), ".geojson"))
+		}
+	case path == "/v0/job" || strings.HasPrefix(path, "/v0/job/"):
+		if s.authorizeAPI(w, r) {
+			s.serveJob(w, r, strings.TrimPrefix(strings.TrimPrefix(path, "/v0/job"), "/"))
+		}
+	case strings.HasPrefix(path, "/planet/discover/"):
+		if s.authorizeBroker(w, r) {
+			s.serveDiscover(w, r, strings.TrimPrefix(path, "/planet/discover/"))
+		}
+	case strings.HasPrefix(path, "/planet/"):
+		if s.authorizeBroker(w, r) {
+			s.serveScene(w, r, strings.TrimPrefix(path, "/planet/"))
+		}
+	case path == stacPath || strings.HasPrefix(path, stacPath+"/"):
+		s.serveSTAC(w, r, strings.TrimPrefix(path, stacPath))
+	case strings.HasPrefix(path, bandsPath):
+		s.serveBand(w, r, strings.TrimPrefix(path, bandsPath))
+	default:
+		writeError(w, http.StatusNotFound, "no such endpoint: "+path)
+	}
+}
+
+// the status to fail the request with, or 0; the caller holds the lock
+func (s *Server) injectedFailure(path string) int {
+	for i, f := range s.failures {
+		if !strings.HasPrefix(path, f.prefix) {
+			continue
+		}
+		if f.count > 0 {
+			f.count--
+			if f.count == 0 {
+				s.failures = append(s.failures[:i], s.failures[i+1:]...)
+			}
+		}
+		return f.status
+	}
+	return 0
+}
+
+// the bf-api takes its key as the basic auth user name
+func (s *Server) authorizeAPI(w http.ResponseWriter, r *http.Request) bool {
+	user, _, ok := r.BasicAuth()
+	if !ok || user != Auth {
+		writeError(w, http.StatusUnauthorized, "missing or wrong API key")
+		return false
+	}
+	return true
+}
+
+// the broker takes the Planet key in the ways set by SetBrokerAuth
+func (s *Server) authorizeBroker(w http.ResponseWriter, r *http.Request) bool {
+	s.mu.Lock()
+	ways := s.brokerAuth
+	s.mu.Unlock()
+
+	keys := []string{}
+	if ways["query"] {
+		keys = append(keys, r.URL.Query().Get("PL_API_KEY"))
+	}
+	if user, _, ok := r.BasicAuth(); ok && ways["basic"] {
+		keys = append(keys, user)
+	}
+	if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "api-key ") && ways["header"] {
+		keys = append(keys, strings.TrimPrefix(auth, "api-key "))
+	}
+	found := false
+	for _, key := range keys {
+		found = found || key == PlanetKey
+	}
+	if !found {
+		writeError(w, http.StatusUnauthorized, "missing or wrong Planet key")
+		return false
+	}
+	return true
+}
@@ -0,0 +1,165 @@
+/* Copyright 2017, RadiantBlue Technologies, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bftest
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func get(t *testing.T, s *Server, path string, key string) (int, string) {
+	req, err := http.NewRequest("GET", s.URL+path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key != "" {
+		req.SetBasicAuth(key, "")
+	}
+	resp, err := s.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	byts, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode, string(byts)
+}
+
+func TestServerAuth(t *testing.T) {
+	assert := assert.New(t)
+
+	s := NewServer()
+	defer s.Close()
+
+	status, _ := get(t, s, "/v0/job", "")
+	assert.Equal(http.StatusUnauthorized, status)
+	status, _ = get(t, s, "/v0/job", Auth)
+	assert.Equal(http.StatusOK, status)
+
+	s.AddScene(&Scene{Catalog: "landsat", Id: "LC1"})
+	status, _ = get(t, s, "/planet/landsat/LC1", Auth)
+	assert.Equal(http.StatusUnauthorized, status)
+	status, _ = get(t, s, "/planet/landsat/LC1?PL_API_KEY="+PlanetKey, "")
+	assert.Equal(http.StatusOK, status)
+
+	// by default, also as the basic auth user, or in a header...
+	status, _ = get(t, s, "/planet/landsat/LC1", PlanetKey)
+	assert.Equal(http.StatusOK, status)
+	req, err := http.NewRequest("GET", s.URL+"/planet/landsat/LC1", nil)
+	assert.NoError(err)
+	req.Header.Set("Authorization", "api-key "+PlanetKey)
+	resp, err := s.Client().Do(req)
+	assert.NoError(err)
+	resp.Body.Close()
+	assert.Equal(http.StatusOK, resp.StatusCode)
+
+	// ...unless it is to take only the parameter
+	s.SetBrokerAuth("query")
+	status, _ = get(t, s, "/planet/landsat/LC1", PlanetKey)
+	assert.Equal(http.StatusUnauthorized, status)
+	status, _ = get(t, s, "/planet/landsat/LC1?PL_API_KEY="+PlanetKey, "")
+	assert.Equal(http.StatusOK, status)
+}
+
+func TestServerJobProgress(t *testing.T) {
+	assert := assert.New(t)
+
+	s := NewServer()
+	defer s.Close()
+
+	id := s.AddJob(&Job{Name: "j", Then: []string{"Running", "Success"}})
+
+	statuses := []string{}
+	for i := 0; i < 4; i++ {
+		_, body := get(t, s, "/v0/job/"+id, Auth)
+		obj := struct{ Job *jobFeature }{}
+		assert.NoError(json.Unmarshal([]byte(body), &obj))
+		statuses = append(statuses, obj.Job.Properties.Status)
+	}
+	assert.Equal([]string{"Submitted", "Running", "Success", "Success"}, statuses)
+
+	s.SetJobStatus(id, "Error")
+	assert.Equal("Error", s.Job(id).Status)
+}
+
+func TestServerFail(t *testing.T) {
+	assert := assert.New(t)
+
+	s := NewServer()
+	defer s.Close()
+
+	s.Fail("/v0/algorithm", http.StatusBadGateway, 2)
+	statuses := []int{}
+	for i := 0; i < 3; i++ {
+		status, _ := get(t, s, "/v0/algorithm", Auth)
+		statuses = append(statuses, status)
+	}
+	assert.Equal([]int{502, 502, 200}, statuses)
+
+	s.Fail("/", http.StatusInternalServerError, -1)
+	status, body := get(t, s, "/v0/job", Auth)
+	assert.Equal(500, status)
+	assert.Contains(body, "injected failure")
+	s.ClearFailures()
+
+	s.SetLatency(20 * time.Millisecond)
+	start := time.Now()
+	status, _ = get(t, s, "/v0/job", Auth)
+	assert.Equal(200, status)
+	assert.True(time.Since(start) >= 20*time.Millisecond)
+	assert.Len(s.Requests(), 5)
+}
+
+func TestServerSTAC(t *testing.T) {
+	assert := assert.New(t)
+
+	s := NewServer()
+	defer s.Close()
+
+	day := time.Date(2017, 8, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 3; i++ {
+		s.AddScene(&Scene{Catalog: "landsat", Id: "LC" + string(rune('1'+i)), AcquiredDate: day.AddDate(0, 0, i), CloudCover: float64(i * 10)})
+	}
+
+	page := struct {
+		Features []struct{ Id string }
+		Links    []stacLink
+	}{}
+	status, body := get(t, s, `/stac/search?collections=landsat&limit=1&query={"eo:cloud_cover":{"lte":10}}`, "")
+	assert.Equal(http.StatusOK, status)
+	assert.NoError(json.Unmarshal([]byte(body), &page))
+	assert.Len(page.Features, 1)
+	assert.Equal("LC2", page.Features[0].Id) // newest first
+	assert.Len(page.Links, 1)
+	assert.Equal("next", page.Links[0].Rel)
+
+	status, body = get(t, s, "/stac/search?datetime=../2017-08-01T00:00:00Z", "")
+	assert.Equal(http.StatusOK, status)
+	assert.Contains(body, `"numberMatched":1`)
+
+	status, _ = get(t, s, "/stac/collections/landsat/items/LC1", "")
+	assert.Equal(http.StatusOK, status)
+	status, body = get(t, s, "/stac/collections/landsat/items/LC9", "")
+	assert.Equal(http.StatusNotFound, status)
+	assert.Contains(body, `"code":"NotFound"`)
+}
@@ -0,0 +1,286 @@
+/* Copyright 2017, RadiantBlue Technologies, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bftest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The server is a STAC API too, under stacPath, serving the scenes as
+// items: each catalog is a collection, and each band a data asset. It
+// needs no key, as public STAC APIs do not.
+const stacPath = "/stac"
+
+// STACURL returns the base URL of the STAC API.
+func (s *Server) STACURL() string {
+	return s.URL + stacPath
+}
+
+// STAC's wire form of a scene
+type stacItem struct {
+	Type           string                 `json:"type"`
+	STACVersion    string                 `json:"stac_version"`
+	STACExtensions []string               `json:"stac_extensions"`
+	Id             string                 `json:"id"`
+	Collection     string                 `json:"collection"`
+	Geometry       map[string]interface{} `json:"geometry"`
+	Bbox           [4]float64             `json:"bbox"`
+	Properties     map[string]interface{} `json:"properties"`
+	Links          []stacLink             `json:"links"`
+	Assets         map[string]stacAsset   `json:"assets"`
+}
+
+type stacLink struct {
+	Rel    string `json:"rel"`
+	Href   string `json:"href"`
+	Type   string `json:"type,omitempty"`
+	Method string `json:"method,omitempty"`
+}
+
+type stacAsset struct {
+	Href  string   `json:"href"`
+	Type  string   `json:"type,omitempty"`
+	Roles []string `json:"roles,omitempty"`
+}
+
+const (
+	stacVersion   = "1.0.0"
+	eoExtension   = "https://stac-extensions.github.io/eo/v1.0.0/schema.json"
+	geotiffType   = "image/tiff; application=geotiff"
+	geojsonType   = "application/geo+json"
+	thumbnailFile = "thumbnail.png"
+)
+
+// the item for a scene, with links on the given server. Besides the bands
+// there is a thumbnail, which is not data.
+func (sc *Scene) item(base string) *stacItem {
+	f := sc.feature(base)
+	self := fmt.Sprintf("%s%s/collections/%s/items/%s", base, stacPath, sc.Catalog, sc.Id)
+	item := &stacItem{
+		Type:           "Feature",
+		STACVersion:    stacVersion,
+		STACExtensions: []string{eoExtension},
+		Id:             sc.Id,
+		Collection:     sc.Catalog,
+		Geometry:       f.Geometry,
+		Bbox:           sc.Bbox,
+		Properties: map[string]interface{}{
+			"datetime":       f.Properties.AcquiredDate,
+			"eo:cloud_cover": sc.CloudCover,
+			"gsd":            sc.Resolution,
+			"platform":       sc.SensorName,
+		},
+		Links: []stacLink{
+			{Rel: "self", Href: self, Type: geojsonType},
+			{Rel: "collection", Href: fmt.Sprintf("%s%s/collections/%s", base, stacPath, sc.Catalog)},
+		},
+		Assets: map[string]stacAsset{
+			"thumbnail": {Href: fmt.Sprintf("%s%s%s/%s/%s", base, bandsPath, sc.Catalog, sc.Id, thumbnailFile), Type: "image/png", Roles: []string{"thumbnail"}},
+		},
+	}
+	for name, href := range f.Properties.Bands {
+		item.Assets[name] = stacAsset{Href: href, Type: geotiffType, Roles: []string{"data"}}
+	}
+	return item
+}
+
+//---------------------------------------------------------------------
+
+func (s *Server) serveSTAC(w http.ResponseWriter, r *http.Request, path string) {
+	if r.Method != "GET" {
+		writeSTACError(w, http.StatusMethodNotAllowed, "MethodNotAllowed", "only GET is supported")
+		return
+	}
+
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	switch {
+	case path == "" || path == "/":
+		base := baseURL(r) + stacPath
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"type":         "Catalog",
+			"stac_version": stacVersion,
+			"id":           "bftest",
+			"description":  "bftest scenes",
+			"conformsTo": []string{
+				"https://api.stacspec.org/v1.0.0/core",
+				"https://api.stacspec.org/v1.0.0/item-search",
+				"https://api.stacspec.org/v1.0.0/item-search#query",
+			},
+			"links": []stacLink{
+				{Rel: "self", Href: base},
+				{Rel: "search", Href: base + "/search", Type: geojsonType, Method: "GET"},
+			},
+		})
+	case path == "/search":
+		s.serveSTACSearch(w, r)
+	case len(parts) == 4 && parts[0] == "collections" && parts[2] == "items":
+		s.mu.Lock()
+		sc := s.scene(parts[1], parts[3])
+		s.mu.Unlock()
+		if sc == nil {
+			writeSTACError(w, http.StatusNotFound, "NotFound", "item not found: "+parts[1]+"/"+parts[3])
+			return
+		}
+		writeJSON(w, http.StatusOK, sc.item(baseURL(r)))
+	default:
+		writeSTACError(w, http.StatusNotFound, "NotFound", "no such endpoint: "+r.URL.Path)
+	}
+}
+
+// /stac/search, filtered by collections, ids, bbox, datetime and a query
+// on eo:cloud_cover, a page of limit items at a time. The server's page
+// size, if set, is the most a page may hold.
+func (s *Server) serveSTACSearch(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+
+	bbox, err := parseBbox(params.Get("bbox"))
+	if err != nil {
+		writeSTACError(w, http.StatusBadRequest, "InvalidParameterValue", err.Error())
+		return
+	}
+
+	filter := &sceneFilter{maxCloudCover: 100, bbox: bbox}
+
+	if v := params.Get("datetime"); v != "" {
+		filter.after, filter.before, err = parseInterval(v)
+		if err != nil {
+			writeSTACError(w, http.StatusBadRequest, "InvalidParameterValue", err.Error())
+			return
+		}
+	}
+
+	if v := params.Get("query"); v != "" {
+		query := map[string]map[string]float64{}
+		err := json.Unmarshal([]byte(v), &query)
+		if err != nil {
+			writeSTACError(w, http.StatusBadRequest, "InvalidParameterValue", "bad query: "+v)
+			return
+		}
+		for property, ops := range query {
+			for op, value := range ops {
+				if property != "eo:cloud_cover" || op != "lte" {
+					writeSTACError(w, http.StatusBadRequest, "InvalidParameterValue", "unsupported query: "+property+" "+op)
+					return
+				}
+				filter.maxCloudCover = value
+			}
+		}
+	}
+
+	limit := 0
+	if v := params.Get("limit"); v != "" {
+		limit, err = strconv.Atoi(v)
+		if err != nil || limit < 1 {
+			writeSTACError(w, http.StatusBadRequest, "InvalidParameterValue", "bad limit: "+v)
+			return
+		}
+	}
+
+	page := 1
+	if v := params.Get("page"); v != "" {
+		page, err = strconv.Atoi(v)
+		if err != nil || page < 1 {
+			writeSTACError(w, http.StatusBadRequest, "InvalidParameterValue", "bad page: "+v)
+			return
+		}
+	}
+
+	ids := map[string]bool{}
+	if v := params.Get("ids"); v != "" {
+		for _, id := range strings.Split(v, ",") {
+			ids[id] = true
+		}
+	}
+
+	s.mu.Lock()
+	collections := []string{}
+	if v := params.Get("collections"); v != "" {
+		collections = strings.Split(v, ",")
+	} else {
+		for name := range s.scenes {
+			collections = append(collections, name)
+		}
+	}
+	matches := []*Scene{}
+	for _, sc := range s.findScenes(collections, filter) {
+		if len(ids) == 0 || ids[sc.Id] {
+			matches = append(matches, sc)
+		}
+	}
+	size := s.pageSize
+	s.mu.Unlock()
+
+	if limit > 0 && (size == 0 || limit < size) {
+		size = limit
+	}
+	found := len(matches)
+	matches, more := pageOf(matches, page, size)
+
+	base := baseURL(r)
+	links := []stacLink{}
+	if more {
+		params.Set("page", strconv.Itoa(page+1))
+		next := base + (&url.URL{Path: r.URL.Path, RawQuery: params.Encode()}).String()
+		links = append(links, stacLink{Rel: "next", Href: next, Type: geojsonType, Method: "GET"})
+	}
+
+	features := []*stacItem{}
+	for _, sc := range matches {
+		features = append(features, sc.item(base))
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"type":           "FeatureCollection",
+		"features":       features,
+		"links":          links,
+		"numberMatched":  found,
+		"numberReturned": len(features),
+	})
+}
+
+// a STAC datetime: one instant, or "<start>/<end>" with ".." or nothing
+// for an open end. One instant is returned as both ends.
+func parseInterval(v string) (time.Time, time.Time, error) {
+	var ends [2]time.Time
+	parts := strings.Split(v, "/")
+	if len(parts) > 2 {
+		return ends[0], ends[1], fmt.Errorf("bad datetime: %s", v)
+	}
+	for i, part := range parts {
+		if part == "" || part == ".." {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, part)
+		if err != nil {
+			return ends[0], ends[1], fmt.Errorf("bad datetime: %s", v)
+		}
+		ends[i] = t
+	}
+	if len(parts) == 1 {
+		ends[1] = ends[0]
+	}
+	return ends[0], ends[1], nil
+}
+
+// errors are written as STAC APIs do, {"code": "...", "description": "..."}
+func writeSTACError(w http.ResponseWriter, status int, code string, description string) {
+	writeJSON(w, status, map[string]string{"code": code, "description": description})
+}
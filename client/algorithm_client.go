@@ -16,14 +16,16 @@ limitations under the License.
 package client
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
-	"log"
+	"net/http"
 )
 
 type AlgorithmClient struct {
-	url  string
-	auth string
+	url        string
+	auth       string
+	httpClient *http.Client
 }
 
 type Algorithms struct {
@@ -62,59 +64,84 @@ func (a *AlgorithmInfo) String() string {
 //---------------------------------------------------------------------
 
 func NewAlgorithmClient() (*AlgorithmClient, error) {
+	cfg, err := loadConfig([]string{"auth"}, "api_url")
+	if err != nil {
+		return nil, err
+	}
+	return newAlgorithmClient(cfg.withHTTPClient())
+}
+
+func newAlgorithmClient(cfg *Config) (*AlgorithmClient, error) {
 
-	fields, err := ReadBeachfrontrcFields([]string{"domain", "auth"})
+	err := cfg.require("auth")
 	if err != nil {
 		return nil, err
 	}
 
-	url := "https://" + apiServer + "." + fields["domain"]
+	url, err := cfg.serviceURL(cfg.APIURL, "api_url", apiServer)
+	if err != nil {
+		return nil, err
+	}
 
 	return &AlgorithmClient{
-		url:  url,
-		auth: fields["auth"],
+		url:        url,
+		auth:       cfg.Auth,
+		httpClient: cfg.HTTPClient,
 	}, nil
 }
 
 //---------------------------------------------------------------------
 
-func (c *AlgorithmClient) GetInfoForAll() (string, error) {
+func (c *AlgorithmClient) GetInfoForAll() (*Algorithms, error) {
+	return c.GetInfoForAllContext(context.Background())
+}
 
-	log.Print("Algorithm.GetInfoForAll")
+// GetInfoForAllContext is GetInfoForAll with a context, for cancellation and deadlines.
+func (c *AlgorithmClient) GetInfoForAllContext(ctx context.Context) (*Algorithms, error) {
+
+	debugf("Algorithm.GetInfoForAll")
 	path := "/v0/algorithm"
 	url := fmt.Sprintf("%s%s", c.url, path)
 
-	jsn, err := doHttpGetJSONWithAuth(url, c.auth, 200)
+	jsn, err := doHttpGetJSONWithAuth(ctx, c.httpClient, url, c.auth, 200)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	obj := &Algorithms{}
 	err = json.Unmarshal([]byte(jsn), obj)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	return obj.String(), nil
+	return obj, nil
+}
+
+func (c *AlgorithmClient) GetInfoForOne(id string) (*AlgorithmInfo, error) {
+	return c.GetInfoForOneContext(context.Background(), id)
 }
 
-func (c *AlgorithmClient) GetInfoForOne(id string) (string, error) {
+// GetInfoForOneContext is GetInfoForOne with a context, for cancellation and deadlines.
+func (c *AlgorithmClient) GetInfoForOneContext(ctx context.Context, id string) (*AlgorithmInfo, error) {
 
-	log.Print("Algorithm.GetInfoForOne")
+	debugf("Algorithm.GetInfoForOne")
 
 	path := "/v0/algorithm"
 	url := fmt.Sprintf("%s%s/%s", c.url, path, id)
 
-	jsn, err := doHttpGetJSONWithAuth(url, c.auth, 200)
+	jsn, err := doHttpGetJSONWithAuth(ctx, c.httpClient, url, c.auth, 200)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	obj := &Algorithm{}
 	err = json.Unmarshal([]byte(jsn), obj)
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+	if obj.Algorithm == nil {
+		return nil, fmt.Errorf("algorithm response contains no algorithm")
 	}
 
-	return obj.String(), nil
+	return obj.Algorithm, nil
 }
@@ -16,8 +16,7 @@ limitations under the License.
 package client
 
 import (
-	"encoding/json"
-	"log"
+	"errors"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -26,22 +25,14 @@ import (
 func TestAlgorithmInfoForAll(t *testing.T) {
 	assert := assert.New(t)
 
-	c, err := NewAlgorithmClient()
-	assert.NoError(err)
-
-	s, err := c.GetInfoForAll()
-	assert.NoError(err)
+	c, _ := newTestClient(t)
 
-	obj := map[string]interface{}{}
-	err = json.Unmarshal([]byte(s), &obj)
+	algs, err := c.Algorithm.GetInfoForAll()
 	assert.NoError(err)
 
-	obj2 := obj["algorithms"].([]interface{})
-
 	found := false
-	for _, v := range obj2 {
-		obj3 := v.(map[string]interface{})["name"]
-		if obj3 == "NDWI_PY" {
+	for _, v := range algs.Algorithms {
+		if v.Name == "NDWI_PY" {
 			found = true
 			break
 		}
@@ -52,27 +43,15 @@ func TestAlgorithmInfoForAll(t *testing.T) {
 func TestAlgorithmInfoForOne(t *testing.T) {
 	assert := assert.New(t)
 
-	c, err := NewAlgorithmClient()
-	assert.NoError(err)
-
-	// TODO: get id from a getall call(), not hard-coded here
-	s, err := c.GetInfoForOne("f64d4845-0b9d-4bf1-8d49-45bafd639875")
-	assert.NoError(err)
+	c, _ := newTestClient(t)
 
-	obj := map[string]interface{}{}
-	err = json.Unmarshal([]byte(s), &obj)
+	alg, err := c.Algorithm.GetInfoForOne(testAlgorithmId)
 	assert.NoError(err)
-	log.Print(s)
 
-	obj2 := obj["algorithms"].([]interface{})
+	assert.Equal(testAlgorithmId, alg.ServiceId)
+	assert.Equal("NDWI_PY", alg.Name)
+	assert.Equal(10, alg.MaxCloudCover)
 
-	found := false
-	for _, v := range obj2 {
-		obj3 := v.(map[string]interface{})["name"]
-		if obj3 == "NDWI_PY" {
-			found = true
-			break
-		}
-	}
-	assert.True(found)
+	_, err = c.Algorithm.GetInfoForOne("no-such-algorithm")
+	assert.True(errors.Is(err, ErrNotFound))
 }
@@ -0,0 +1,362 @@
+/* Copyright 2017, RadiantBlue Technologies, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// The .beachfrontrc file is either a flat map of fields:
+//
+//   {"domain": "int.geointservices.io", "auth": "...", "planet_key": "..."}
+//
+// or a set of named profiles, each such a map:
+//
+//   {
+//     "default_profile": "int",
+//     "profiles": {
+//       "int":  {"domain": "int.geointservices.io", ...},
+//       "prod": {"domain": "geointservices.io", ...}
+//     }
+//   }
+//
+// A flat file is treated as a single profile named "default".
+
+// ProfileEnvVar names the environment variable that selects a profile.
+const ProfileEnvVar = "BEACHFRONT_PROFILE"
+
+const defaultProfile = "default"
+
+// the profile chosen by SelectProfile
+var selectedProfile string
+
+// SelectProfile chooses the .beachfrontrc profile used by the clients. It
+// takes precedence over $BEACHFRONT_PROFILE and the file's default
+// profile. An empty name clears the choice.
+func SelectProfile(name string) {
+	selectedProfile = name
+}
+
+// Fields lists the .beachfrontrc fields the clients use.
+var Fields = []string{"domain", "auth", "planet_key"}
+
+// OptionalFields lists the fields that may be left out: the base URL of
+// each service, which otherwise derives from the domain, how the Planet
+// key is sent to the broker, the catalog provider, and where the stac and
+// local providers find scenes.
+var OptionalFields = []string{"api_url", "broker_url", "catalog_auth", "provider", "stac_url", "local_dir"}
+
+// AllFields returns Fields followed by OptionalFields.
+func AllFields() []string {
+	return append(append([]string{}, Fields...), OptionalFields...)
+}
+
+// SecretFields are the fields to redact when showing settings.
+var SecretFields = map[string]bool{"auth": true, "planet_key": true}
+
+// Beachfrontrc is the contents of the .beachfrontrc file.
+type Beachfrontrc struct {
+	DefaultProfile string                       `json:"default_profile,omitempty"`
+	Profiles       map[string]map[string]string `json:"profiles"`
+}
+
+// BeachfrontrcPath returns where the .beachfrontrc file is kept:
+// $HOME/.beachfrontrc, or else $XDG_CONFIG_HOME/beachfront/beachfrontrc
+// (by default ~/.config/beachfront/beachfrontrc). If neither exists, it is
+// the one in $HOME.
+func BeachfrontrcPath() string {
+	home := os.Getenv("HOME") + "/.beachfrontrc"
+	if _, err := os.Stat(home); err == nil {
+		return home
+	}
+	xdg := xdgBeachfrontrcPath()
+	if _, err := os.Stat(xdg); err == nil {
+		return xdg
+	}
+	return home
+}
+
+func xdgBeachfrontrcPath() string {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		dir = os.Getenv("HOME") + "/.config"
+	}
+	return filepath.Join(dir, "beachfront", "beachfrontrc")
+}
+
+// LoadBeachfrontrc reads the .beachfrontrc file. A missing file is not an
+// error: it gives an empty Beachfrontrc, ready to be filled in and saved.
+func LoadBeachfrontrc() (*Beachfrontrc, error) {
+	rc, err := readBeachfrontrc()
+	if os.IsNotExist(err) {
+		return &Beachfrontrc{Profiles: map[string]map[string]string{}}, nil
+	}
+	return rc, err
+}
+
+// Save writes the file, readable only by its owner. A file holding just
+// the "default" profile is written in the flat form.
+func (rc *Beachfrontrc) Save() error {
+	var v interface{} = rc
+	if len(rc.Profiles) == 1 && (rc.DefaultProfile == "" || rc.DefaultProfile == defaultProfile) {
+		if flat, ok := rc.Profiles[defaultProfile]; ok {
+			v = flat
+		}
+	}
+
+	byts, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(BeachfrontrcPath(), os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	err = file.Chmod(0600)
+	if err == nil {
+		_, err = file.Write(append(byts, '\n'))
+	}
+	cerr := file.Close()
+	if err != nil {
+		return err
+	}
+	return cerr
+}
+
+// ProfileName returns the name of the profile in use: the one given to
+// SelectProfile, then $BEACHFRONT_PROFILE, then the file's default.
+func (rc *Beachfrontrc) ProfileName() string {
+	return rc.profileName()
+}
+
+// Get returns a field of the profile in use, or "" if it is not set.
+func (rc *Beachfrontrc) Get(field string) string {
+	return rc.Profiles[rc.profileName()][field]
+}
+
+// Set changes a field of the profile in use, creating the profile if need
+// be.
+func (rc *Beachfrontrc) Set(field string, value string) {
+	name := rc.profileName()
+	if rc.Profiles == nil {
+		rc.Profiles = map[string]map[string]string{}
+	}
+	if rc.Profiles[name] == nil {
+		rc.Profiles[name] = map[string]string{}
+	}
+	rc.Profiles[name][field] = value
+}
+
+// Validate checks that the profile in use has every field, and that they
+// are well formed. It returns all of the problems found.
+func (rc *Beachfrontrc) Validate() []error {
+	name := rc.profileName()
+	profile, ok := rc.Profiles[name]
+	if !ok {
+		return []error{fmt.Errorf("no profile '%s' in .beachfrontrc", name)}
+	}
+	return validateFields(fmt.Sprintf("profile '%s'", name), profile)
+}
+
+// checks a set of fields for missing, unknown and malformed ones; what
+// names the set in the messages. The fields needed are those NewClient
+// needs: auth, those of the provider, and the domain unless every URL it
+// would derive is given.
+func validateFields(what string, fields map[string]string) []error {
+	problems := []error{}
+	required := []string{"auth"}
+	urlFields := []string{"api_url"}
+	if t, err := lookupProvider(fields["provider"]); err == nil {
+		required = append(required, t.Fields...)
+		urlFields = append(urlFields, t.URLFields...)
+	}
+	for _, field := range urlFields {
+		if fields[field] == "" {
+			required = append([]string{"domain"}, required...)
+			break
+		}
+	}
+	for _, field := range required {
+		if fields[field] == "" {
+			problems = append(problems, fmt.Errorf("%s is missing '%s'", what, field))
+		}
+	}
+	unknown := []string{}
+	for field := range fields {
+		if !isField(field) {
+			unknown = append(unknown, field)
+		}
+	}
+	sort.Strings(unknown)
+	for _, field := range unknown {
+		problems = append(problems, fmt.Errorf("%s has unknown field '%s'", what, field))
+	}
+	if domain := fields["domain"]; domain != "" && !domainPattern.MatchString(domain) {
+		problems = append(problems, fmt.Errorf("%s has a malformed domain '%s': expected a host name such as int.geointservices.io", what, domain))
+	}
+	if how := fields["catalog_auth"]; how != "" {
+		_, err := planetCredential(how, "")
+		if err != nil {
+			problems = append(problems, fmt.Errorf("%s: %s", what, err.Error()))
+		}
+	}
+	if name := fields["provider"]; name != "" {
+		_, err := lookupProvider(name)
+		if err != nil {
+			problems = append(problems, fmt.Errorf("%s: %s", what, err.Error()))
+		}
+	}
+	for _, field := range []string{"api_url", "broker_url", "stac_url"} {
+		if value := fields[field]; value != "" {
+			_, err := parseBaseURL(value)
+			if err != nil {
+				problems = append(problems, fmt.Errorf("%s has a malformed %s: %s", what, field, err.Error()))
+			}
+		}
+	}
+
+	return problems
+}
+
+// a DNS name with at least two labels, and no scheme, port or path
+var domainPattern = regexp.MustCompile(`^(?i)([a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?\.)+[a-z]{2,63}$`)
+
+func isField(field string) bool {
+	for _, f := range AllFields() {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+// Redact hides all but the last four characters of a secret.
+func Redact(value string) string {
+	if len(value) < 12 {
+		return "****"
+	}
+	return "****" + value[len(value)-4:]
+}
+
+func readBeachfrontrc() (*Beachfrontrc, error) {
+	file, err := os.Open(BeachfrontrcPath())
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	byts, err := ioutil.ReadAll(file)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseBeachfrontrc(byts)
+}
+
+func parseBeachfrontrc(byts []byte) (*Beachfrontrc, error) {
+	top := map[string]json.RawMessage{}
+	err := json.Unmarshal(byts, &top)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse .beachfrontrc: %s", err.Error())
+	}
+
+	if _, ok := top["profiles"]; !ok {
+		flat := map[string]string{}
+		err = json.Unmarshal(byts, &flat)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse .beachfrontrc: %s", err.Error())
+		}
+		return &Beachfrontrc{
+			DefaultProfile: defaultProfile,
+			Profiles:       map[string]map[string]string{defaultProfile: flat},
+		}, nil
+	}
+
+	rc := &Beachfrontrc{}
+	err = json.Unmarshal(byts, rc)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse .beachfrontrc: %s", err.Error())
+	}
+	if rc.Profiles == nil {
+		rc.Profiles = map[string]map[string]string{}
+	}
+	return rc, nil
+}
+
+// profileName picks the profile: SelectProfile, then $BEACHFRONT_PROFILE,
+// then the file's default_profile, then "default"
+func (rc *Beachfrontrc) profileName() string {
+	switch {
+	case selectedProfile != "":
+		return selectedProfile
+	case os.Getenv(ProfileEnvVar) != "":
+		return os.Getenv(ProfileEnvVar)
+	case rc.DefaultProfile != "":
+		return rc.DefaultProfile
+	}
+	return defaultProfile
+}
+
+func (rc *Beachfrontrc) profile() (string, map[string]string, error) {
+	name := rc.profileName()
+	profile, ok := rc.Profiles[name]
+	if !ok {
+		names := []string{}
+		for k := range rc.Profiles {
+			names = append(names, k)
+		}
+		sort.Strings(names)
+		return "", nil, fmt.Errorf("no profile '%s' in .beachfrontrc (profiles: %s)", name, strings.Join(names, ", "))
+	}
+	return name, profile, nil
+}
+
+// ReadBeachfrontrcFields returns the fields from the selected profile of
+// the .beachfrontrc file alone. The clients use LoadSettings, which also
+// takes flags, environment variables and defaults into account.
+//
+// if any one field fails, the whole thing fails
+func ReadBeachfrontrcFields(fields []string) (map[string]string, error) {
+	rc, err := readBeachfrontrc()
+	if err != nil {
+		return nil, err
+	}
+
+	name, profile, err := rc.profile()
+	if err != nil {
+		return nil, err
+	}
+
+	results := map[string]string{}
+
+	for _, field := range fields {
+		value, ok := profile[field]
+		if !ok || value == "" {
+			return nil, fmt.Errorf("Missing item in .beachfrontrc profile '%s': '%s'", name, field)
+		}
+		results[field] = value
+	}
+
+	return results, nil
+}
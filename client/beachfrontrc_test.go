@@ -0,0 +1,219 @@
+/* Copyright 2017, RadiantBlue Technologies, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// points $HOME at a temporary directory holding the given .beachfrontrc
+func withBeachfrontrc(t *testing.T, contents string) {
+	dir, err := ioutil.TempDir("", "bf-client")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	err = ioutil.WriteFile(filepath.Join(dir, ".beachfrontrc"), []byte(contents), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("HOME", dir)
+	t.Setenv("XDG_CONFIG_HOME", "")
+	t.Setenv(ProfileEnvVar, "")
+	for _, field := range AllFields() {
+		t.Setenv(EnvVar(field), "")
+	}
+	SelectProfile("")
+	SetOverrides(nil)
+	t.Cleanup(func() {
+		SelectProfile("")
+		SetOverrides(nil)
+	})
+}
+
+func TestBeachfrontrcFlat(t *testing.T) {
+	assert := assert.New(t)
+
+	withBeachfrontrc(t, `{"domain": "int.example.com", "auth": "abc", "planet_key": "xyz"}`)
+
+	fields, err := ReadBeachfrontrcFields([]string{"domain", "auth"})
+	assert.NoError(err)
+	assert.Equal("int.example.com", fields["domain"])
+	assert.Equal("abc", fields["auth"])
+
+	_, err = ReadBeachfrontrcFields([]string{"domain", "nope"})
+	assert.Error(err)
+
+	SelectProfile("prod")
+	_, err = ReadBeachfrontrcFields([]string{"domain"})
+	assert.Error(err)
+}
+
+func TestBeachfrontrcProfiles(t *testing.T) {
+	assert := assert.New(t)
+
+	withBeachfrontrc(t, `{
+		"default_profile": "int",
+		"profiles": {
+			"int":   {"domain": "int.example.com", "auth": "a1"},
+			"stage": {"domain": "stage.example.com", "auth": "a2"},
+			"prod":  {"domain": "example.com", "auth": "a3"}
+		}
+	}`)
+
+	fields, err := ReadBeachfrontrcFields([]string{"domain"})
+	assert.NoError(err)
+	assert.Equal("int.example.com", fields["domain"])
+
+	t.Setenv(ProfileEnvVar, "stage")
+	fields, err = ReadBeachfrontrcFields([]string{"domain"})
+	assert.NoError(err)
+	assert.Equal("stage.example.com", fields["domain"])
+
+	// an explicit choice wins over the environment
+	SelectProfile("prod")
+	fields, err = ReadBeachfrontrcFields([]string{"domain", "auth"})
+	assert.NoError(err)
+	assert.Equal("example.com", fields["domain"])
+	assert.Equal("a3", fields["auth"])
+
+	SelectProfile("nope")
+	_, err = ReadBeachfrontrcFields([]string{"domain"})
+	assert.Error(err)
+	assert.Contains(err.Error(), "int, prod, stage")
+}
+
+func TestBeachfrontrcProfileConstructors(t *testing.T) {
+	assert := assert.New(t)
+
+	withBeachfrontrc(t, `{
+		"profiles": {
+			"default": {"domain": "int.example.com", "auth": "a1", "planet_key": "p1"},
+			"prod":    {"domain": "example.com", "auth": "a3", "planet_key": "p3"}
+		}
+	}`)
+
+	SelectProfile("prod")
+
+	c, err := NewClient()
+	assert.NoError(err)
+	assert.Equal("https://bf-api.example.com", c.Job.(*JobClient).url)
+	assert.Equal("https://bf-ia-broker.example.com", c.Catalog.(*CatalogClient).provider.(*PlanetProvider).url)
+	assert.Equal("p3", c.Catalog.(*CatalogClient).provider.(*PlanetProvider).credential.(*HeaderCredential).Key)
+}
+
+func TestBeachfrontrcSave(t *testing.T) {
+	assert := assert.New(t)
+
+	withBeachfrontrc(t, `{"domain": "int.example.com", "auth": "abc", "planet_key": "xyz"}`)
+
+	rc, err := LoadBeachfrontrc()
+	assert.NoError(err)
+	assert.Equal("default", rc.ProfileName())
+	assert.Equal("abc", rc.Get("auth"))
+
+	// a lone default profile stays flat
+	rc.Set("auth", "def")
+	assert.NoError(rc.Save())
+	byts, err := ioutil.ReadFile(BeachfrontrcPath())
+	assert.NoError(err)
+	assert.NotContains(string(byts), "profiles")
+
+	stat, err := os.Stat(BeachfrontrcPath())
+	assert.NoError(err)
+	assert.Equal(os.FileMode(0600), stat.Mode().Perm())
+
+	// a second profile switches to the profiles form
+	SelectProfile("prod")
+	rc.Set("domain", "example.com")
+	assert.NoError(rc.Save())
+
+	rc, err = LoadBeachfrontrc()
+	assert.NoError(err)
+	assert.Equal("example.com", rc.Get("domain"))
+	SelectProfile("")
+	assert.Equal("def", rc.Get("auth"))
+}
+
+func TestBeachfrontrcMissing(t *testing.T) {
+	assert := assert.New(t)
+
+	withBeachfrontrc(t, "")
+	os.Remove(BeachfrontrcPath())
+
+	rc, err := LoadBeachfrontrc()
+	assert.NoError(err)
+	assert.Empty(rc.Profiles)
+	assert.Len(rc.Validate(), 1)
+
+	_, err = ReadBeachfrontrcFields([]string{"domain"})
+	assert.Error(err)
+}
+
+func TestBeachfrontrcValidate(t *testing.T) {
+	assert := assert.New(t)
+
+	withBeachfrontrc(t, `{"domain": "int.example.com", "auth": "abc", "planet_key": "xyz"}`)
+	rc, err := LoadBeachfrontrc()
+	assert.NoError(err)
+	assert.Empty(rc.Validate())
+
+	for _, domain := range []string{"https://int.example.com", "int.example.com/", "localhost", "int example.com", "int.example.com:443"} {
+		rc.Set("domain", domain)
+		assert.Len(rc.Validate(), 1, domain)
+	}
+
+	rc.Set("domain", "int.example.com")
+	rc.Set("auth", "")
+	rc.Set("colour", "blue")
+	assert.Len(rc.Validate(), 2)
+
+	// other providers need their own settings, not Planet's
+	rc.Set("auth", "abc")
+	delete(rc.Profiles[rc.ProfileName()], "colour")
+	rc.Set("planet_key", "")
+	rc.Set("provider", "local")
+	problems := rc.Validate()
+	assert.Len(problems, 1)
+	assert.EqualError(problems[0], "profile 'default' is missing 'local_dir'")
+	rc.Set("local_dir", "/data/scenes")
+	assert.Empty(rc.Validate())
+
+	rc.Set("provider", "stac")
+	problems = rc.Validate()
+	assert.Len(problems, 1)
+	assert.EqualError(problems[0], "profile 'default' is missing 'stac_url'")
+
+	// with no broker, the API URL is all the domain is needed for
+	rc.Set("stac_url", "https://stac.example.com/v1")
+	rc.Set("domain", "")
+	rc.Set("api_url", "http://localhost:8080")
+	assert.Empty(rc.Validate())
+}
+
+func TestRedact(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal("****", Redact("short"))
+	assert.Equal("****cdef", Redact("0123456789abcdef"))
+}
@@ -16,39 +16,56 @@ limitations under the License.
 package client
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
-	"io/ioutil"
-	"log"
-	"os"
+	"net/url"
+	"path"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/venicegeo/bf-client/geojson"
+
 	"gopkg.in/urfave/cli.v1"
 )
 
-const catalogServer = "bf-ia-broker"
-
-const catalogTimeout = time.Duration(2 * time.Minute)
-
+// CatalogClient answers the catalog calls with a CatalogProvider: the
+// broker's Planet catalogs unless the settings name another.
 type CatalogClient struct {
-	url      string // "https://<bf-ia-broker>.<int.geointservices.io>"
-	keyParam string // "PL_API_KEY=<123abc>"
+	provider CatalogProvider
 }
 
 func NewCatalogClient() (*CatalogClient, error) {
-
-	fields, err := ReadBeachfrontrcFields([]string{"domain", "planet_key"})
+	cfg, err := loadCatalogConfig(nil)
 	if err != nil {
 		return nil, err
 	}
+	return newCatalogClient(cfg.withHTTPClient())
+}
 
-	keyParam := "PL_API_KEY=" + fields["planet_key"]
+// NewCatalogClientWithProvider returns a client using the provider given,
+// one of one's own or a registered one built by hand.
+func NewCatalogClientWithProvider(provider CatalogProvider) *CatalogClient {
+	return &CatalogClient{provider: provider}
+}
+
+func newCatalogClient(cfg *Config) (*CatalogClient, error) {
+	t, err := lookupProvider(cfg.Provider)
+	if err != nil {
+		return nil, err
+	}
+	provider, err := t.New(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &CatalogClient{provider: provider}, nil
+}
 
-	return &CatalogClient{
-		url:      "https://" + catalogServer + "." + fields["domain"],
-		keyParam: keyParam,
-	}, nil
+// Provider returns the provider the client uses.
+func (c *CatalogClient) Provider() CatalogProvider {
+	return c.provider
 }
 
 //---------------------------------------------------------------------
@@ -68,7 +85,7 @@ func (c *Catalog) String() string {
 
 type CatalogFeature struct {
 	Type       string
-	Geometry   *GeometryInfo
+	Geometry   *geojson.Geometry
 	Properties *PropertiesInfo
 	Id         string
 	Bbox       [4]float64
@@ -78,62 +95,13 @@ func (c *CatalogFeature) String() string {
 	return fmt.Sprintf("[catalog-feature %s]", c.Id)
 }
 
-type Point []float64
-type PointList []Point
-type Any interface{}
-type AnyList []Any
-
-type GeometryInfo struct {
-	Type        string
-	Coordinates []AnyList // either [][]Point or [][]PointList
-}
-
 type PropertiesInfo struct {
 	AcquiredDate string
-	Bands        map[string]string
+	Bands        map[string]string // band name -> download URL
 	CloudCover   float64
 	FileFormat   string
-	resolution   float64
-	sensorName   string
-}
-
-type SceneInfo struct {
-	Properties PropertiesInfo
-}
-
-//---------------------------------------------------------------------
-
-func toPoint(v interface{}) (Point, bool) {
-	point, ok := v.([]interface{})
-	if !ok {
-		return nil, false
-	}
-	x, ok := point[0].(float64)
-	if !ok {
-		return nil, false
-	}
-	y, ok := point[1].(float64)
-	if !ok {
-		return nil, false
-	}
-	return Point{x, y}, true
-}
-
-func toPointList(v interface{}) ([]Point, bool) {
-	list, ok := v.([]interface{})
-	if !ok {
-		return nil, false
-	}
-
-	cl := []Point{}
-	for _, v := range list {
-		point, ok := toPoint(v)
-		if !ok {
-			return nil, false
-		}
-		cl = append(cl, point)
-	}
-	return cl, true
+	Resolution   float64
+	SensorName   string
 }
 
 //---------------------------------------------------------------------
@@ -153,130 +121,360 @@ func splitId(id string) (string, string, error) {
 
 func (c *CatalogClient) GetInfoForCatalogs() error {
 
-	log.Printf("Catalog.GetInfoForCatalogs")
+	debugf("Catalog.GetInfoForCatalogs")
 
 	return cli.NewExitError("catalog: --info for catalogs not yet supported", 2)
 }
 
-func (c *CatalogClient) GetInfoForScene(id string) (string, error) {
+func (c *CatalogClient) GetInfoForScene(id string) (*CatalogFeature, error) {
+	return c.GetInfoForSceneContext(context.Background(), id)
+}
+
+// GetInfoForSceneContext is GetInfoForScene with a context, for cancellation and deadlines.
+func (c *CatalogClient) GetInfoForSceneContext(ctx context.Context, id string) (*CatalogFeature, error) {
 
-	log.Printf("Catalog.GetInfoForScene")
+	debugf("Catalog.GetInfoForScene")
 
-	sensor, scene, err := splitId(id)
+	_, _, err := splitId(id)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	path := "/planet/" + sensor + "/" + scene
+	obj, err := c.provider.SceneInfo(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if obj.Properties == nil {
+		return nil, fmt.Errorf("scene %s has no properties", id)
+	}
 
-	params := c.keyParam
+	return obj, nil
+}
 
-	url := fmt.Sprintf("%s%s?%s", c.url, path, params)
+// GetInfoForCatalog returns the scenes in the catalog, as a search with
+// no filters.
+func (c *CatalogClient) GetInfoForCatalog(id string) (*Catalog, error) {
+	return c.GetInfoForCatalogContext(context.Background(), id)
+}
 
-	jsn, err := doHttpGetJSON(url, catalogTimeout, 200)
+// GetInfoForCatalogContext is GetInfoForCatalog with a context, for cancellation and deadlines.
+func (c *CatalogClient) GetInfoForCatalogContext(ctx context.Context, id string) (*Catalog, error) {
 
-	obj := &Catalog{}
-	err = json.Unmarshal([]byte(jsn), obj)
-	if err != nil {
-		return "", err
-	}
+	debugf("Catalog.GetInfoForCatalog")
 
-	return obj.String(), nil
+	return c.provider.Search(ctx, id, &SearchQuery{})
 }
 
-func (c *CatalogClient) GetInfoForCatalog(id string) (string, error) {
+// SearchQuery restricts a catalog search. Zero values are not sent.
+type SearchQuery struct {
+	MaxCloudCover  *float64  // percent, nil for any; see CloudCover
+	Bbox           []float64 // minx, miny, maxx, maxy; minx > maxx crosses the antimeridian
+	AcquiredAfter  time.Time
+	AcquiredBefore time.Time
+	Limit          int // maximum number of features returned, 0 for all
+}
+
+// CloudCover returns a pointer to the percent, for SearchQuery.MaxCloudCover:
+// CloudCover(0) asks for cloud-free scenes only.
+func CloudCover(percent float64) *float64 {
+	return &percent
+}
+
+func (q *SearchQuery) values() (url.Values, error) {
+	params := url.Values{}
 
-	log.Printf("Catalog.GetInfoForCatalog")
+	if cc := q.MaxCloudCover; cc != nil {
+		if *cc < 0 || *cc > 100 {
+			return nil, fmt.Errorf("cloud cover must be between 0 and 100: %g", *cc)
+		}
+		params.Set("cloudCover", strconv.FormatFloat(*cc, 'f', -1, 64))
+	}
+
+	if q.Bbox != nil {
+		if len(q.Bbox) != 4 {
+			return nil, fmt.Errorf("bbox must have four values: minx, miny, maxx, maxy")
+		}
+		if q.Bbox[1] > q.Bbox[3] {
+			return nil, fmt.Errorf("bbox miny must not exceed maxy")
+		}
+		bbox := make([]string, 4)
+		for i, v := range q.Bbox {
+			bbox[i] = strconv.FormatFloat(v, 'f', -1, 64)
+		}
+		params.Set("bbox", strings.Join(bbox, ","))
+	}
 
-	path := "/planet/discover/" + id
+	if !q.AcquiredAfter.IsZero() {
+		params.Set("acquiredDate", q.AcquiredAfter.UTC().Format(time.RFC3339))
+	}
+	if !q.AcquiredBefore.IsZero() {
+		params.Set("maxAcquiredDate", q.AcquiredBefore.UTC().Format(time.RFC3339))
+	}
 
-	params := c.keyParam
+	if q.Limit < 0 {
+		return nil, fmt.Errorf("limit must not be negative: %d", q.Limit)
+	}
 
-	url := fmt.Sprintf("%s%s?%s", c.url, path, params)
+	return params, nil
+}
 
-	jsn, err := doHttpGetJSON(url, catalogTimeout, 200)
-	if err != nil {
-		return "", err
+// matches says whether the scene passes the query's filters, as the broker
+// applies them, for catalogs searched without one. A scene whose footprint
+// is not known passes the bbox filter rather than be judged by an empty
+// one. The limit is not a filter, and is left to the caller.
+func (q *SearchQuery) matches(f *CatalogFeature) bool {
+	p := f.Properties
+	if p == nil {
+		p = &PropertiesInfo{}
 	}
 
-	obj := &Catalog{}
-	err = json.Unmarshal([]byte(jsn), obj)
-	if err != nil {
-		return "", err
-	}
-
-	for _, f := range obj.Features {
-		newCoordinates := make([]AnyList, len(f.Geometry.Coordinates))
-		for i, anyArray := range f.Geometry.Coordinates {
-			newArray := make(AnyList, len(anyArray))
-			for j, any := range anyArray {
-				point, ok := toPoint(any)
-				if ok {
-					fmt.Printf("%f %f\n", point[0], point[1])
-					newArray[j] = point
-				} else {
-					pointList, ok := toPointList(any)
-					if ok {
-						fmt.Printf("** %#v\n", pointList)
-						newArray[j] = pointList
-					} else {
-						fmt.Printf("=== %T === %v ===", any, any)
-						panic(17)
-					}
-				}
+	if q.MaxCloudCover != nil && p.CloudCover > *q.MaxCloudCover {
+		return false
+	}
+	footprint := f.Geometry != nil || f.Bbox != [4]float64{}
+	if len(q.Bbox) == 4 && footprint && !bboxesOverlap(f.Bbox[:], q.Bbox) {
+		return false
+	}
+	if !q.AcquiredAfter.IsZero() || !q.AcquiredBefore.IsZero() {
+		acquired, err := time.Parse(time.RFC3339Nano, p.AcquiredDate)
+		if err != nil {
+			return false
+		}
+		if !q.AcquiredAfter.IsZero() && acquired.Before(q.AcquiredAfter) {
+			return false
+		}
+		if !q.AcquiredBefore.IsZero() && acquired.After(q.AcquiredBefore) {
+			return false
+		}
+	}
+	return true
+}
+
+// whether two bboxes overlap. Either may cross the antimeridian, as in
+// GeoJSON, its minx then being greater than its maxx.
+func bboxesOverlap(a []float64, b []float64) bool {
+	if a[1] > b[3] || a[3] < b[1] {
+		return false
+	}
+	for _, x := range lonRanges(a[0], a[2]) {
+		for _, y := range lonRanges(b[0], b[2]) {
+			if x[0] <= y[1] && y[0] <= x[1] {
+				return true
 			}
-			newCoordinates[i] = newArray
 		}
-		f.Geometry.Coordinates = newCoordinates
 	}
-	os.Exit(9)
-	return obj.String(), nil
+	return false
+}
+
+// the longitudes from min to max, in two ranges if they cross 180°
+func lonRanges(min float64, max float64) [][2]float64 {
+	if min <= max {
+		return [][2]float64{{min, max}}
+	}
+	return [][2]float64{{min, 180}, {-180, max}}
+}
+
+// SearchCatalog returns the scenes in the catalog matching the query,
+// reading every page of results until the query's limit is reached.
+func (c *CatalogClient) SearchCatalog(id string, query *SearchQuery) (*Catalog, error) {
+	return c.SearchCatalogContext(context.Background(), id, query)
 }
 
-// returns map: file name -> file size
-func (c *CatalogClient) DoCatalogSceneDownload(id string) (map[string]int, error) {
+// SearchCatalogContext is SearchCatalog with a context, for cancellation and deadlines.
+func (c *CatalogClient) SearchCatalogContext(ctx context.Context, id string, query *SearchQuery) (*Catalog, error) {
 
-	log.Printf("Catalog.DoSceneDownload")
+	debugf("Catalog.SearchCatalog")
 
-	body, err := c.GetInfoForScene(id)
+	if query == nil {
+		query = &SearchQuery{}
+	}
 
-	info := SceneInfo{}
-	err = json.Unmarshal([]byte(body), &info)
+	_, err := query.values()
 	if err != nil {
 		return nil, err
 	}
 
-	fileInfo := map[string]int{}
+	return c.provider.Search(ctx, id, query)
+}
 
-	i := 1
-	for bandName, value := range info.Properties.Bands {
+// one band of a scene download
+type bandDownload struct {
+	asset *Asset
+	path  string // where it is written
+}
 
-		status, byts, err := doHttpGetBytes(value, catalogTimeout)
+// DoCatalogSceneDownload streams each band of the scene to a file, several
+// bands at a time, named as the options say. Interrupted downloads are
+// resumed from their ".part" files when run again. If some bands fail, the
+// ones that finished are still returned, along with an error naming the
+// failures. The results are sorted by file. If the context is cancelled,
+// no more bands are started, those in progress are abandoned and their
+// partial files removed, and the finished ones are returned with the
+// context's error.
+func (c *CatalogClient) DoCatalogSceneDownload(id string, opts *DownloadOptions) ([]*DownloadResult, error) {
+	return c.DoCatalogSceneDownloadContext(context.Background(), id, opts)
+}
+
+// DoCatalogSceneDownloadContext is DoCatalogSceneDownload with a context, for cancellation and deadlines.
+func (c *CatalogClient) DoCatalogSceneDownloadContext(ctx context.Context, id string, opts *DownloadOptions) ([]*DownloadResult, error) {
+
+	debugf("Catalog.DoSceneDownload")
+
+	if opts == nil {
+		opts = &DownloadOptions{}
+	}
+	err := opts.validate()
+	if err != nil {
+		return nil, err
+	}
+
+	_, _, err = splitId(id)
+	if err != nil {
+		return nil, err
+	}
+
+	assets, err := c.provider.ListAssets(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return downloadBands(ctx, id, assets, opts, c.provider.DownloadAsset)
+}
+
+// writes an asset to the file at path, returning its size
+type assetFetcher func(ctx context.Context, asset *Asset, path string, progress ProgressReporter) (int64, error)
+
+// downloads the assets of the scene with the given id, as
+// DoCatalogSceneDownload says, fetching each one with fetch
+func downloadBands(ctx context.Context, id string, assets []*Asset, opts *DownloadOptions, fetch assetFetcher) ([]*DownloadResult, error) {
+	if opts == nil {
+		opts = &DownloadOptions{}
+	}
+	err := opts.validate()
+	if err != nil {
+		return nil, err
+	}
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = defaultDownloadWorkers
+	}
+
+	sensor, scene, err := splitId(id)
+	if err != nil {
+		return nil, err
+	}
+	results := []*DownloadResult{}
+	bands := []*bandDownload{}
+	paths := map[string]string{} // path -> band
+	existing := []string{}
+
+	for _, asset := range assets {
+		bandName, value := asset.Name, asset.URL
+		u, err := url.Parse(value)
 		if err != nil {
-			return nil, err
+			return nil, fmt.Errorf("unable to parse URL: %s", value)
 		}
-		if status != 200 {
-			return nil, fmt.Errorf("HTTP download failed with status %d", status)
+		filename := path.Base(u.Path)
+		if filename == "." || filename == "/" {
+			return nil, fmt.Errorf("unable to parse URL path: %s", value)
 		}
 
-		idx := strings.LastIndex(value, "/")
-		if idx == -1 {
-			return nil, fmt.Errorf("unable to parse URL path: %s", value)
+		vars := map[string]string{
+			"catalog": sensor,
+			"scene":   scene,
+			"band":    bandName,
+			"file":    filename,
+		}
+		outPath, err := opts.outputPath(SceneTemplate, vars)
+		if err != nil {
+			return nil, err
+		}
+		if other, ok := paths[outPath]; ok {
+			return nil, fmt.Errorf("bands %s and %s would both be written to %s", other, bandName, outPath)
 		}
-		filename := value[idx+1 : len(value)]
+		paths[outPath] = bandName
 
-		// TODO: fix path root
-		err = ioutil.WriteFile("./"+filename, byts, 0600)
+		size, err := existingSize(outPath)
 		if err != nil {
 			return nil, err
 		}
+		if size >= 0 {
+			switch opts.Existing {
+			case ExistingSkip:
+				verbosef("%s: %s exists, skipping", bandName, outPath)
+				results = append(results, &DownloadResult{File: outPath, Band: bandName, Bytes: size, Skipped: true})
+				continue
+			case ExistingFail, "":
+				existing = append(existing, outPath)
+				continue
+			}
+		}
 
-		fileInfo[filename] = len(byts)
+		bands = append(bands, &bandDownload{asset: asset, path: outPath})
+	}
+
+	if len(existing) > 0 {
+		sort.Strings(existing)
+		return nil, fmt.Errorf("files already exist, use skip or overwrite: %s", strings.Join(existing, ", "))
+	}
+
+	failures := []string{}
+	var firstErr error
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+
+	queue := make(chan *bandDownload)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for band := range queue {
+				err := prepareOutputPath(band.path)
+				var size int64
+				if err == nil {
+					size, err = fetch(ctx, band.asset, band.path, opts.Progress)
+				}
 
-		log.Printf("%d/%d: %s\n", i, len(info.Properties.Bands), bandName)
+				mutex.Lock()
+				if err != nil {
+					verbosef("%s: %s", band.asset.Name, err.Error())
+					failures = append(failures, band.asset.Name)
+					if firstErr == nil {
+						firstErr = err
+					}
+				} else {
+					results = append(results, &DownloadResult{File: band.path, Band: band.asset.Name, Bytes: size})
+					if opts.Progress == nil {
+						verbosef("%d/%d: %s", len(results), len(paths), band.asset.Name)
+					}
+				}
+				mutex.Unlock()
+			}
+		}()
+	}
+
+queueing:
+	for _, band := range bands {
+		select {
+		case queue <- band:
+		case <-ctx.Done():
+			break queueing
+		}
+	}
+	close(queue)
+	wg.Wait()
+
+	sort.Slice(results, func(i, j int) bool { return results[i].File < results[j].File })
+
+	if ctx.Err() != nil {
+		return results, ctx.Err()
+	}
 
-		i++
+	if len(failures) > 0 {
+		sort.Strings(failures)
+		return results, fmt.Errorf("%d of %d bands failed to download: %s (first error: %w)",
+			len(failures), len(paths), strings.Join(failures, ", "), firstErr)
 	}
 
-	return fileInfo, nil
+	return results, nil
 }
@@ -16,69 +16,326 @@ limitations under the License.
 package client
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/venicegeo/bf-client/bftest"
+	"github.com/venicegeo/bf-client/geojson"
 )
 
 func TestCatalogInfoForCatalogs(t *testing.T) {
 	assert := assert.New(t)
 
-	c, err := NewCatalogClient()
-	assert.NoError(err)
+	c, _ := newTestClient(t)
 
-	err = c.GetInfoForCatalogs()
+	err := c.Catalog.GetInfoForCatalogs()
 	assert.Error(err)
 }
 
 func TestCatalogInfoForScene(t *testing.T) {
 	assert := assert.New(t)
 
-	c, err := NewCatalogClient()
+	c, _ := newTestClient(t)
+
+	feature, err := c.Catalog.GetInfoForScene(testSceneId)
 	assert.NoError(err)
 
-	s, err := c.GetInfoForScene("landsat:LC81260322017212LGN00")
+	assert.Equal("Feature", feature.Type)
+	assert.Equal("LC81260322017212LGN00", feature.Id)
+	assert.Equal("Polygon", feature.Geometry.Type())
+	assert.NotEmpty(feature.Properties.Bands)
+
+	_, err = c.Catalog.GetInfoForScene("landsat:LC80000000000000LGN00")
+	assert.True(errors.Is(err, ErrNotFound))
+}
+
+func TestCatalogInfoForCatalog(t *testing.T) {
+	assert := assert.New(t)
+
+	c, _ := newTestClient(t)
+
+	catalog, err := c.Catalog.GetInfoForCatalog("landsat")
 	assert.NoError(err)
 
-	obj := map[string]interface{}{}
-	err = json.Unmarshal([]byte(s), &obj)
+	assert.NotEmpty(catalog.Features)
+	assert.Equal("Feature", catalog.Features[0].Type)
+	assert.NotNil(catalog.Features[0].Geometry)
+}
+
+func TestCatalogFeatureDecode(t *testing.T) {
+	assert := assert.New(t)
+
+	const jsn = `{
+		"type": "Feature",
+		"id": "LC81260322017212LGN00",
+		"geometry": {"type": "Polygon", "coordinates": [[[116.1,40.6],[118.3,40.2],[117.7,38.5],[115.6,38.9],[116.1,40.6]]]},
+		"bbox": [115.6, 38.5, 118.3, 40.6],
+		"properties": {
+			"acquiredDate": "2017-07-31T02:58:35.870114+00:00",
+			"bands": {"coastal": "https://example.com/LC81260322017212LGN00_B1.TIF"},
+			"cloudCover": 3.4,
+			"fileFormat": "geotiff",
+			"resolution": 30,
+			"sensorName": "Landsat8"
+		}
+	}`
+
+	feature := &CatalogFeature{}
+	err := json.Unmarshal([]byte(jsn), feature)
 	assert.NoError(err)
 
-	assert.Equal("Feature", obj["type"])
-	assert.Equal("LC81260322017212LGN00", obj["id"])
+	poly, ok := feature.Geometry.Shape.(*geojson.Polygon)
+	assert.True(ok)
+	assert.Len(poly.Coordinates[0], 5)
+	assert.Equal(38.5, feature.Bbox[1])
+	assert.Equal(3.4, feature.Properties.CloudCover)
+	assert.Equal(30.0, feature.Properties.Resolution)
+	assert.Equal("Landsat8", feature.Properties.SensorName)
+	assert.Equal("https://example.com/LC81260322017212LGN00_B1.TIF", feature.Properties.Bands["coastal"])
 }
 
-func TestCatalogInfoForCatalog(t *testing.T) {
+func TestCatalogSearchQuery(t *testing.T) {
+	assert := assert.New(t)
+
+	q := &SearchQuery{
+		MaxCloudCover: CloudCover(10),
+		Bbox:          []float64{-120.5, 30, -110, 40.25},
+		AcquiredAfter: time.Date(2017, 6, 1, 0, 0, 0, 0, time.UTC),
+	}
+	params, err := q.values()
+	assert.NoError(err)
+	assert.Equal("10", params.Get("cloudCover"))
+	assert.Equal("-120.5,30,-110,40.25", params.Get("bbox"))
+	assert.Equal("2017-06-01T00:00:00Z", params.Get("acquiredDate"))
+	assert.Equal("", params.Get("maxAcquiredDate"))
+
+	q = &SearchQuery{Bbox: []float64{1, 2, 3}}
+	_, err = q.values()
+	assert.Error(err)
+
+	q = &SearchQuery{MaxCloudCover: CloudCover(101)}
+	_, err = q.values()
+	assert.Error(err)
+
+	q = &SearchQuery{Limit: -1}
+	_, err = q.values()
+	assert.Error(err)
+
+	// crossing the antimeridian is allowed, but not south above north
+	q = &SearchQuery{Bbox: []float64{170, -20, -170, -10}}
+	params, err = q.values()
+	assert.NoError(err)
+	assert.Equal("170,-20,-170,-10", params.Get("bbox"))
+	q = &SearchQuery{Bbox: []float64{-10, 20, 10, 10}}
+	_, err = q.values()
+	assert.Error(err)
+}
+
+func TestBboxesOverlap(t *testing.T) {
+	fiji := []float64{177, -19, -179, -16} // crosses the antimeridian
+	tests := []struct {
+		bbox    []float64
+		overlap bool
+	}{
+		{[]float64{178, -18, 179, -17}, true},
+		{[]float64{-179.5, -18, -179.2, -17}, true},
+		{[]float64{170, -30, -170, 0}, true},
+		{[]float64{0, -18, 1, -17}, false},
+		{[]float64{-178, -18, 176, -17}, false},
+		{[]float64{178, 0, 179, 1}, false},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.overlap, bboxesOverlap(fiji, test.bbox), "%v", test.bbox)
+		assert.Equal(t, test.overlap, bboxesOverlap(test.bbox, fiji), "%v", test.bbox)
+	}
+}
+
+func TestCatalogSearchRepeatedNext(t *testing.T) {
+	assert := assert.New(t)
+
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write([]byte(`{"type": "FeatureCollection", "features": [{"id": "LC1"}],
+			"_links": {"_next": "/planet/discover/landsat?page=2"}}`))
+	}))
+	defer server.Close()
+
+	p := &PlanetProvider{url: server.URL, httpClient: server.Client()}
+	catalog, err := p.Search(context.Background(), "landsat", &SearchQuery{})
+	assert.NoError(err)
+	assert.Len(catalog.Features, 2)
+	assert.Equal(2, requests)
+}
+
+func TestCatalogNextPage(t *testing.T) {
+	assert := assert.New(t)
+
+	c := &PlanetProvider{url: "https://broker.example.com"}
+	base, err := url.Parse("https://broker.example.com/planet/discover/landsat?cloudCover=10")
+	assert.NoError(err)
+
+	next, err := c.nextPage(base, "")
+	assert.NoError(err)
+	assert.Equal("", next)
+
+	next, err = c.nextPage(base, "/planet/discover/landsat?page=2")
+	assert.NoError(err)
+	assert.Equal("https://broker.example.com/planet/discover/landsat?page=2", next)
+
+	// a key echoed back by the broker is dropped
+	next, err = c.nextPage(base, "/planet/discover/landsat?PL_API_KEY=abc123&page=2")
+	assert.NoError(err)
+	assert.Equal("https://broker.example.com/planet/discover/landsat?page=2", next)
+
+	// behind a proxy, links from the root keep the prefix
+	c.url = "https://proxy.example.com/beachfront"
+	base, err = url.Parse("https://proxy.example.com/beachfront/planet/discover/landsat")
+	assert.NoError(err)
+
+	next, err = c.nextPage(base, "/planet/discover/landsat?page=2")
+	assert.NoError(err)
+	assert.Equal("https://proxy.example.com/beachfront/planet/discover/landsat?page=2", next)
+
+	next, err = c.nextPage(base, "/beachfront/planet/discover/landsat?page=3")
+	assert.NoError(err)
+	assert.Equal("https://proxy.example.com/beachfront/planet/discover/landsat?page=3", next)
+
+	next, err = c.nextPage(base, "landsat?page=4")
+	assert.NoError(err)
+	assert.Equal("https://proxy.example.com/beachfront/planet/discover/landsat?page=4", next)
+}
+
+func TestCatalogSearch(t *testing.T) {
 	assert := assert.New(t)
 
-	c, err := NewCatalogClient()
+	c, server := newTestClient(t)
+
+	day := time.Date(2017, 8, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 9; i++ {
+		server.AddScene(&bftest.Scene{
+			Catalog:      "landsat",
+			Id:           fmt.Sprintf("LC8126033201721%dLGN00", i),
+			AcquiredDate: day.AddDate(0, 0, i),
+			CloudCover:   float64(i * 5),
+			Bbox:         [4]float64{115, 38, 118, 40},
+		})
+	}
+	server.SetPageSize(2)
+
+	// every page is read: five of those, and the scene the server starts with...
+	catalog, err := c.Catalog.SearchCatalog("landsat", &SearchQuery{MaxCloudCover: CloudCover(20)})
 	assert.NoError(err)
+	assert.Len(catalog.Features, 6)
+	for _, f := range catalog.Features {
+		assert.True(f.Properties.CloudCover <= 20)
+	}
 
-	s, err := c.GetInfoForCatalog("landsat")
+	// ...until the limit is reached
+	catalog, err = c.Catalog.SearchCatalog("landsat", &SearchQuery{MaxCloudCover: CloudCover(20), Limit: 3})
 	assert.NoError(err)
+	assert.Len(catalog.Features, 3)
 
-	obj := map[string]interface{}{}
-	err = json.Unmarshal([]byte(s), &obj)
+	// zero is cloud-free, not any
+	catalog, err = c.Catalog.SearchCatalog("landsat", &SearchQuery{MaxCloudCover: CloudCover(0)})
 	assert.NoError(err)
+	assert.Len(catalog.Features, 1)
 
-	obj2 := obj["features"].([]interface{})[0]
-	assert.IsType(map[string]interface{}{}, obj2)
-	obj3 := obj2.(map[string]interface{})
+	catalog, err = c.Catalog.SearchCatalog("landsat", &SearchQuery{
+		AcquiredAfter: day.AddDate(0, 0, 7),
+		Bbox:          []float64{117, 39, 120, 41},
+	})
+	assert.NoError(err)
+	assert.Len(catalog.Features, 2)
 
-	//fmt.Println(obj3)
-	assert.Equal("Feature", obj3["type"])
+	_, err = c.Catalog.SearchCatalog("sentinel", nil)
+	assert.True(errors.Is(err, ErrNotFound))
+}
+
+func TestCatalogSceneDownloadToDirectory(t *testing.T) {
+	assert := assert.New(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/planet/landsat/LC81260322017212LGN00":
+			fmt.Fprintf(w, `{"type": "Feature", "id": "LC81260322017212LGN00", "properties": {"bands": {
+				"coastal": "http://%s/bands/B1.TIF",
+				"blue": "http://%s/bands/B2.TIF",
+				"broken": "http://%s/bands/missing.TIF"
+			}}}`, r.Host, r.Host, r.Host)
+		case "/bands/B1.TIF", "/bands/B2.TIF":
+			w.Write([]byte("geotiff"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "bf-client")
+	assert.NoError(err)
+	defer os.RemoveAll(dir)
+
+	c := NewCatalogClientWithProvider(&PlanetProvider{url: server.URL, credential: &HeaderCredential{Key: "abc123"}, httpClient: server.Client()})
+	opts := &DownloadOptions{OutputDir: dir, Template: "{catalog}/{scene}/{band}.TIF"}
+
+	// one band fails, the others are kept
+	results, err := c.DoCatalogSceneDownload("landsat:LC81260322017212LGN00", opts)
+	assert.Error(err)
+	assert.Contains(err.Error(), "broken")
+	assert.Len(results, 2)
+
+	coastal := filepath.Join(dir, "landsat", "LC81260322017212LGN00", "coastal.TIF")
+	assert.Equal(coastal, results[1].File)
+	assert.Equal("coastal", results[1].Band)
+	assert.EqualValues(7, results[1].Bytes)
+	byts, err := ioutil.ReadFile(coastal)
+	assert.NoError(err)
+	assert.Equal("geotiff", string(byts))
+
+	// existing files fail by default...
+	_, err = c.DoCatalogSceneDownload("landsat:LC81260322017212LGN00", opts)
+	assert.Error(err)
+	assert.Contains(err.Error(), "already exist")
+
+	// ...or can be skipped
+	opts.Existing = ExistingSkip
+	results, err = c.DoCatalogSceneDownload("landsat:LC81260322017212LGN00", opts)
+	assert.Error(err)
+	assert.Len(results, 2)
+	assert.True(results[0].Skipped)
+
+	// every band to the same file is refused
+	opts.Template = "{scene}.TIF"
+	_, err = c.DoCatalogSceneDownload("landsat:LC81260322017212LGN00", opts)
+	assert.Error(err)
+	assert.Contains(err.Error(), "both be written")
 }
 
 func TestCatalogSceneDownload(t *testing.T) {
 	assert := assert.New(t)
 
-	c, err := NewCatalogClient()
+	c, _ := newTestClient(t)
+
+	dir, err := ioutil.TempDir("", "bf-client")
 	assert.NoError(err)
+	defer os.RemoveAll(dir)
 
-	t.Skip("downloads take too long and may time out")
-	m, err := c.DoCatalogSceneDownload("landsat:LC81260322017212LGN00")
+	results, err := c.Catalog.DoCatalogSceneDownload(testSceneId, &DownloadOptions{OutputDir: dir, Workers: 2})
 	assert.NoError(err)
+	assert.Len(results, 3)
 
-	assert.Equal(11, m)
+	byts, err := ioutil.ReadFile(filepath.Join(dir, "LC81260322017212LGN00_coastal.TIF"))
+	assert.NoError(err)
+	assert.Equal("B1", string(byts))
 }
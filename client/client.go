@@ -17,39 +17,51 @@ package client
 
 const apiServer = "bf-api"
 
+// Client holds a service of each kind. NewClient fills it with HTTP clients
+// for the real services; tests can use in-memory ones, or their own.
 type Client struct {
-	Catalog   *CatalogClient
-	Job       *JobClient
-	Coastline *CoastlineClient
-	Algorithm *AlgorithmClient
+	Catalog   CatalogService
+	Job       JobService
+	Coastline CoastlineService
+	Algorithm AlgorithmService
 }
 
+// NewClient resolves the settings once and builds every client from them.
 func NewClient() (*Client, error) {
+	cfg, err := loadCatalogConfig([]string{"auth"}, "api_url")
+	if err != nil {
+		return nil, err
+	}
+	return NewClientWithConfig(cfg)
+}
+
+// NewClientWithConfig builds every client from the config. They share one
+// HTTP client, and so one pool of connections.
+func NewClientWithConfig(cfg *Config) (*Client, error) {
 
-	var err error
-	c := &Client{}
+	cfg = cfg.withHTTPClient()
 
-	c.Catalog, err = NewCatalogClient()
+	catalog, err := newCatalogClient(cfg)
 	if err != nil {
 		return nil, err
 	}
 
-	c.Job, err = NewJobClient()
+	job, err := newJobClient(cfg)
 	if err != nil {
 		return nil, err
 	}
 
-	c.Coastline, err = NewCoastlineClient()
+	coastline, err := newCoastlineClient(cfg)
 	if err != nil {
 		return nil, err
 	}
 
-	c.Algorithm, err = NewAlgorithmClient()
+	algorithm, err := newAlgorithmClient(cfg)
 	if err != nil {
 		return nil, err
 	}
 
-	return c, nil
+	return &Client{Catalog: catalog, Job: job, Coastline: coastline, Algorithm: algorithm}, nil
 }
 
 //---------------------------------------------------------------------
@@ -1,14 +1,90 @@
 package client
 
 import (
+	"net/http"
+	"os"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/venicegeo/bf-client/bftest"
 )
 
+// the job, algorithm and scene the fake Beachfront starts with
+const (
+	testJobId       = "6f475d16-d8f1-4f15-9dda-cf9b2d502241"
+	testAlgorithmId = "f64d4845-0b9d-4bf1-8d49-45bafd639875"
+	testSceneId     = "landsat:LC81260322017212LGN00"
+)
+
+// The tests run against bftest alone. Every request to any other host goes
+// to a proxy that is not there, so a test that reaches for the real
+// services fails at once, the same with or without a network.
+func TestMain(m *testing.M) {
+	for _, name := range []string{"HTTP_PROXY", "HTTPS_PROXY", "http_proxy", "https_proxy"} {
+		os.Setenv(name, "http://127.0.0.1:1")
+	}
+	os.Unsetenv("NO_PROXY")
+	os.Unsetenv("no_proxy")
+	os.Exit(m.Run())
+}
+
+// starts a fake Beachfront, closed when the test ends, holding one
+// algorithm, one finished job and one scene, and returns a client for it
+// that does not retry
+func newTestClient(t *testing.T) (*Client, *bftest.Server) {
+	server := bftest.NewServer()
+	t.Cleanup(server.Close)
+
+	server.AddAlgorithm(&bftest.Algorithm{
+		ServiceId:     testAlgorithmId,
+		Name:          "NDWI_PY",
+		Interface:     "pzsvc-ndwi-py",
+		MaxCloudCover: 10,
+		Version:       "0.0",
+	})
+	server.AddScene(&bftest.Scene{
+		Catalog:      "landsat",
+		Id:           "LC81260322017212LGN00",
+		AcquiredDate: time.Date(2017, 7, 31, 2, 58, 35, 0, time.UTC),
+		CloudCover:   3.4,
+		FileFormat:   "geotiff",
+		Resolution:   30,
+		SensorName:   "Landsat8",
+		Bbox:         [4]float64{115.6, 38.5, 118.3, 40.6},
+		Bands:        map[string][]byte{"coastal": []byte("B1"), "blue": []byte("B2"), "green": []byte("B3")},
+	})
+	server.AddJob(&bftest.Job{
+		Id:               testJobId,
+		Name:             "test job",
+		Status:           JobStatusSuccess,
+		AlgorithmName:    "NDWI_PY",
+		AlgorithmVersion: "0.0",
+		SceneId:          testSceneId,
+	})
+
+	c, err := NewClientWithConfig(&Config{
+		APIURL:    server.URL,
+		BrokerURL: server.URL,
+		Auth:      bftest.Auth,
+		PlanetKey: bftest.PlanetKey,
+		Retry:     &RetryPolicy{MaxAttempts: 1},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c, server
+}
+
 func TestClient(t *testing.T) {
 	assert := assert.New(t)
 
+	server := bftest.NewServer()
+	defer server.Close()
+
+	withBeachfrontrc(t, `{}`)
+	SetOverrides(server.Settings())
+
 	c, err := NewClient()
 	assert.NoError(err)
 	assert.NotNil(c)
@@ -17,4 +93,99 @@ func TestClient(t *testing.T) {
 	assert.NotNil(c.Coastline)
 	assert.NotNil(c.Job)
 	assert.NotNil(c.Algorithm)
+
+	jobs, err := c.Job.GetInfoForJobs()
+	assert.NoError(err)
+	assert.Empty(jobs.Features)
+}
+
+func TestJobClientWithSettings(t *testing.T) {
+	assert := assert.New(t)
+
+	server := bftest.NewServer()
+	defer server.Close()
+
+	withBeachfrontrc(t, `{}`)
+	SetOverrides(server.Settings())
+
+	settings, err := LoadSettings()
+	assert.NoError(err)
+	c, err := NewJobClientWithSettings(settings)
+	assert.NoError(err)
+	_, err = c.GetInfoForJobs()
+	assert.NoError(err)
+
+	// the fields are required as by NewJobClient
+	SetOverrides(map[string]string{"api_url": server.URL})
+	settings, err = LoadSettings()
+	assert.NoError(err)
+	_, err = NewJobClientWithSettings(settings)
+	assert.Error(err)
+	assert.Contains(err.Error(), "Missing setting 'auth'")
+}
+
+func TestClientWithConfig(t *testing.T) {
+	assert := assert.New(t)
+
+	cfg := &Config{
+		Domain:              "int.example.com",
+		Auth:                "abc",
+		PlanetKey:           "xyz",
+		Timeout:             time.Minute,
+		MaxIdleConnsPerHost: 2,
+	}
+	c, err := NewClientWithConfig(cfg)
+	assert.NoError(err)
+	assert.Nil(cfg.HTTPClient)
+
+	// one HTTP client, shared by all
+	shared := c.Catalog.(*CatalogClient).provider.(*PlanetProvider).httpClient
+	assert.Equal(time.Minute, shared.Timeout)
+	transport := shared.Transport.(*retryTransport).base.(*traceTransport).base.(*http.Transport)
+	assert.Equal(2, transport.MaxIdleConnsPerHost)
+	assert.Equal(defaultResponseHeaderTimeout, transport.ResponseHeaderTimeout)
+	assert.True(shared == c.Job.(*JobClient).httpClient)
+	assert.True(shared == c.Coastline.(*CoastlineClient).httpClient)
+	assert.True(shared == c.Algorithm.(*AlgorithmClient).httpClient)
+	assert.Equal("https://bf-api.int.example.com", c.Job.(*JobClient).url)
+
+	// a client of one's own is kept, with retries added
+	own := &http.Client{Timeout: 5 * time.Second}
+	c, err = NewClientWithConfig(&Config{Domain: "int.example.com", Auth: "abc", PlanetKey: "xyz", HTTPClient: own})
+	assert.NoError(err)
+	assert.Equal(5*time.Second, c.Algorithm.(*AlgorithmClient).httpClient.Timeout)
+	assert.True(http.DefaultTransport == c.Algorithm.(*AlgorithmClient).httpClient.Transport.(*retryTransport).base.(*traceTransport).base)
+	assert.Nil(own.Transport)
+
+	_, err = NewClientWithConfig(&Config{Domain: "int.example.com", Auth: "abc"})
+	assert.Error(err)
+}
+
+func TestClientServiceURLs(t *testing.T) {
+	assert := assert.New(t)
+
+	c, err := NewClientWithConfig(&Config{
+		Auth:      "abc",
+		PlanetKey: "xyz",
+		APIURL:    "http://localhost:8080/",
+		BrokerURL: "https://proxy.example.com/beachfront/broker",
+	})
+	assert.NoError(err)
+	assert.Equal("http://localhost:8080", c.Job.(*JobClient).url)
+	assert.Equal("http://localhost:8080", c.Algorithm.(*AlgorithmClient).url)
+	assert.Equal("https://proxy.example.com/beachfront/broker", c.Catalog.(*CatalogClient).provider.(*PlanetProvider).url)
+
+	// one URL given, the other from the domain
+	c, err = NewClientWithConfig(&Config{Domain: "int.example.com", Auth: "abc", PlanetKey: "xyz", APIURL: "http://localhost:8080"})
+	assert.NoError(err)
+	assert.Equal("http://localhost:8080", c.Coastline.(*CoastlineClient).url)
+	assert.Equal("https://bf-ia-broker.int.example.com", c.Catalog.(*CatalogClient).provider.(*PlanetProvider).url)
+
+	_, err = NewClientWithConfig(&Config{Auth: "abc", PlanetKey: "xyz", APIURL: "http://localhost:8080"})
+	assert.Error(err)
+
+	for _, bad := range []string{"localhost:8080", "ftp://example.com", "http://", "http://example.com/?x=1", "http://user:pw@example.com"} {
+		_, err = NewClientWithConfig(&Config{Domain: "int.example.com", Auth: "abc", PlanetKey: "xyz", APIURL: bad})
+		assert.Error(err, bad)
+	}
 }
@@ -16,50 +16,138 @@ limitations under the License.
 package client
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
-	"io/ioutil"
-	"log"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/venicegeo/bf-client/geojson"
 )
 
 type CoastlineClient struct {
-	url  string
-	auth string
+	url        string
+	auth       string
+	httpClient *http.Client
 }
 
 func NewCoastlineClient() (*CoastlineClient, error) {
+	cfg, err := loadConfig([]string{"auth"}, "api_url")
+	if err != nil {
+		return nil, err
+	}
+	return newCoastlineClient(cfg.withHTTPClient())
+}
+
+func newCoastlineClient(cfg *Config) (*CoastlineClient, error) {
 
-	fields, err := ReadBeachfrontrcFields([]string{"domain", "auth"})
+	err := cfg.require("auth")
 	if err != nil {
 		return nil, err
 	}
 
-	url := "https://" + apiServer + "." + fields["domain"]
+	url, err := cfg.serviceURL(cfg.APIURL, "api_url", apiServer)
+	if err != nil {
+		return nil, err
+	}
 
 	return &CoastlineClient{
-		url:  url,
-		auth: fields["auth"],
+		url:        url,
+		auth:       cfg.Auth,
+		httpClient: cfg.HTTPClient,
 	}, nil
 }
 
 //---------------------------------------------------------------------
 
-func (c *CoastlineClient) DoDownload(id string) (string, error) {
+// GetCoastline returns the detected coastline of a finished job.
+func (c *CoastlineClient) GetCoastline(id string) (*geojson.FeatureCollection, error) {
+	return c.GetCoastlineContext(context.Background(), id)
+}
+
+// GetCoastlineContext is GetCoastline with a context, for cancellation and deadlines.
+func (c *CoastlineClient) GetCoastlineContext(ctx context.Context, id string) (*geojson.FeatureCollection, error) {
 
-	log.Printf("Coastline.DoDownload")
+	debugf("Coastline.GetCoastline")
 
 	path := "/v0/job"
 	url := fmt.Sprintf("%s%s/%s.geojson", c.url, path, id)
 
-	responseBody, err := doHttpGetJSONWithAuth(url, c.auth, 200)
+	responseBody, err := doHttpGetJSONWithAuth(ctx, c.httpClient, url, c.auth, 200)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	// TODO: fix root path
-	err = ioutil.WriteFile("./"+id+".geojson", []byte(responseBody), 0600)
+	return decodeCoastline(id, strings.NewReader(responseBody))
+}
+
+// DoDownload streams the job's coastline to a file named as the options
+// say, "<id>.geojson" by default. The file is removed again if it does not
+// hold a GeoJSON FeatureCollection.
+func (c *CoastlineClient) DoDownload(id string, opts *DownloadOptions) (*DownloadResult, error) {
+	return c.DoDownloadContext(context.Background(), id, opts)
+}
+
+// DoDownloadContext is DoDownload with a context, for cancellation and deadlines.
+func (c *CoastlineClient) DoDownloadContext(ctx context.Context, id string, opts *DownloadOptions) (*DownloadResult, error) {
+
+	debugf("Coastline.DoDownload")
+
+	if opts == nil {
+		opts = &DownloadOptions{}
+	}
+	err := opts.validate()
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+
+	filename, err := opts.outputPath(CoastlineTemplate, map[string]string{"job": id})
+	if err != nil {
+		return nil, err
 	}
 
-	return fmt.Sprintf("Wrote %d bytes of geojson\n", len(responseBody)), nil
+	skipped, err := checkExisting(filename, opts.Existing)
+	if skipped != nil || err != nil {
+		return skipped, err
+	}
+
+	err = prepareOutputPath(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	path := "/v0/job"
+	url := fmt.Sprintf("%s%s/%s.geojson", c.url, path, id)
+
+	size, err := doHttpDownloadFile(ctx, c.httpClient, url, c.auth, filename, opts.Progress)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	_, err = decodeCoastline(id, file)
+	file.Close()
+	if err != nil {
+		os.Remove(filename)
+		return nil, err
+	}
+
+	return &DownloadResult{File: filename, Bytes: size}, nil
+}
+
+func decodeCoastline(id string, r io.Reader) (*geojson.FeatureCollection, error) {
+	coastline := &geojson.FeatureCollection{}
+	err := json.NewDecoder(r).Decode(coastline)
+	if err != nil {
+		return nil, fmt.Errorf("coastline for job %s is not valid GeoJSON: %s", id, err.Error())
+	}
+	if coastline.Type != "FeatureCollection" {
+		return nil, fmt.Errorf("coastline for job %s is a %s, not a FeatureCollection", id, coastline.Type)
+	}
+	return coastline, nil
 }
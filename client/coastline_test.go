@@ -16,6 +16,10 @@ limitations under the License.
 package client
 
 import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -24,14 +28,26 @@ import (
 func TestCoastlineDownload(t *testing.T) {
 	assert := assert.New(t)
 
-	c, err := NewCoastlineClient()
+	c, server := newTestClient(t)
+
+	const coastline = `{"type": "FeatureCollection", "features": [{"type": "Feature",
+		"geometry": {"type": "LineString", "coordinates": [[116.1, 40.6], [118.3, 40.2]]},
+		"properties": {}}]}`
+	server.SetCoastline(testJobId, []byte(coastline))
+
+	dir, err := ioutil.TempDir("", "bf-client")
 	assert.NoError(err)
+	defer os.RemoveAll(dir)
 
-	const id = "6f475d16-d8f1-4f15-9dda-cf9b2d502241"
+	result, err := c.Coastline.DoDownload(testJobId, &DownloadOptions{OutputDir: dir, Existing: ExistingOverwrite})
+	assert.NoError(err)
+	assert.EqualValues(len(coastline), result.Bytes)
+	assert.Equal(filepath.Join(dir, testJobId+".geojson"), result.File)
 
-	//	t.Skip("downloads take too long and may time out")
-	m, err := c.DoDownload(id)
+	fc, err := c.Coastline.GetCoastline(testJobId)
 	assert.NoError(err)
+	assert.Len(fc.Features, 1)
 
-	assert.Contains(m, "3530022")
+	_, err = c.Coastline.GetCoastline("no-such-job")
+	assert.True(errors.Is(err, ErrNotFound))
 }
@@ -0,0 +1,211 @@
+/* Copyright 2017, RadiantBlue Technologies, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// HTTP defaults, used for any zero field of Config
+const (
+	defaultTimeout               = 2 * time.Minute
+	defaultDialTimeout           = 30 * time.Second
+	defaultResponseHeaderTimeout = 2 * time.Minute
+	defaultIdleConnTimeout       = 90 * time.Second
+	defaultMaxIdleConnsPerHost   = 8
+)
+
+// Config is everything the clients need. Build one by hand to embed
+// bf-client in a service, or with ConfigFromSettings to honor the flags,
+// environment and .beachfrontrc.
+type Config struct {
+	Domain    string // "int.geointservices.io"
+	Auth      string // the Beachfront API key
+	PlanetKey string
+
+	// The base URL of each service, with scheme and, if need be, port and
+	// path prefix: "http://localhost:8080", "https://proxy.example.com/beachfront".
+	// By default they are "https://bf-api.<Domain>" and "https://bf-ia-broker.<Domain>".
+	APIURL    string
+	BrokerURL string
+
+	// How the broker is sent PlanetKey: "header" (the default), "basic",
+	// or "query", as the PL_API_KEY parameter, for a broker that takes
+	// only that. CatalogCredential, if set, is used instead of any.
+	CatalogAuth       string
+	CatalogCredential Credential
+
+	// Provider names the registered CatalogProvider the catalog client
+	// uses, DefaultProvider ("planet") if empty.
+	Provider string
+
+	// STACURL is the base URL of the STAC API the "stac" provider
+	// searches, e.g. "https://earth-search.aws.element84.com/v1".
+	STACURL string
+
+	// LocalDir is the directory the "local" provider indexes.
+	LocalDir string
+
+	// Retry is the policy for idempotent requests, DefaultRetryPolicy if
+	// nil. It applies to HTTPClient too.
+	Retry *RetryPolicy
+
+	// HTTPClient, if set, is used in place of one built from the settings
+	// below, which are then ignored. Its Timeout is not applied to file
+	// downloads.
+	HTTPClient *http.Client
+
+	Timeout               time.Duration // limit on a whole request, but not on a download
+	DialTimeout           time.Duration // limit on making a connection
+	ResponseHeaderTimeout time.Duration // limit on waiting for a response, downloads included
+	IdleConnTimeout       time.Duration // how long an unused connection is kept open
+	MaxIdleConnsPerHost   int           // unused connections kept open per server
+}
+
+// ConfigFromSettings fills in a Config from resolved settings.
+func ConfigFromSettings(settings *Settings) *Config {
+	return &Config{
+		Domain:    settings.Get("domain"),
+		Auth:      settings.Get("auth"),
+		PlanetKey: settings.Get("planet_key"),
+		APIURL:    settings.Get("api_url"),
+		BrokerURL: settings.Get("broker_url"),
+
+		CatalogAuth: settings.Get("catalog_auth"),
+		Provider:    settings.Get("provider"),
+		STACURL:     settings.Get("stac_url"),
+		LocalDir:    settings.Get("local_dir"),
+	}
+}
+
+// loads the settings, checking that the fields are there, and that there
+// is a domain unless each of the URL fields is set
+func loadConfig(fields []string, urlFields ...string) (*Config, error) {
+	settings, err := LoadSettings()
+	if err != nil {
+		return nil, err
+	}
+	return requireConfig(settings, fields, urlFields...)
+}
+
+// as loadConfig, for settings already loaded
+func requireConfig(settings *Settings, fields []string, urlFields ...string) (*Config, error) {
+	for _, field := range urlFields {
+		if settings.Get(field) == "" {
+			fields = append([]string{"domain"}, fields...)
+			break
+		}
+	}
+	_, err := settings.Require(fields)
+	if err != nil {
+		return nil, err
+	}
+	return ConfigFromSettings(settings), nil
+}
+
+// returns a copy of the config holding the one HTTP client to be shared
+// by every client built from it
+func (cfg *Config) withHTTPClient() *Config {
+	c := *cfg
+	if c.HTTPClient == nil {
+		c.HTTPClient = c.newHTTPClient()
+	}
+	httpClient := *c.HTTPClient
+	httpClient.Transport = newRetryTransport(newTraceTransport(httpClient.Transport), c.Retry)
+	c.HTTPClient = &httpClient
+	return &c
+}
+
+func (cfg *Config) newHTTPClient() *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = (&net.Dialer{
+		Timeout:   durationOr(cfg.DialTimeout, defaultDialTimeout),
+		KeepAlive: 30 * time.Second,
+	}).DialContext
+	transport.ResponseHeaderTimeout = durationOr(cfg.ResponseHeaderTimeout, defaultResponseHeaderTimeout)
+	transport.IdleConnTimeout = durationOr(cfg.IdleConnTimeout, defaultIdleConnTimeout)
+	transport.MaxIdleConnsPerHost = defaultMaxIdleConnsPerHost
+	if cfg.MaxIdleConnsPerHost > 0 {
+		transport.MaxIdleConnsPerHost = cfg.MaxIdleConnsPerHost
+	}
+
+	return &http.Client{
+		Transport: transport,
+		Timeout:   durationOr(cfg.Timeout, defaultTimeout),
+	}
+}
+
+func durationOr(d time.Duration, def time.Duration) time.Duration {
+	if d > 0 {
+		return d
+	}
+	return def
+}
+
+// returns the base URL of a service: the one given, or else the server's
+// name under the domain
+func (cfg *Config) serviceURL(base string, field string, server string) (string, error) {
+	if base != "" {
+		u, err := parseBaseURL(base)
+		if err != nil {
+			return "", fmt.Errorf("config has a malformed %s: %s", field, err.Error())
+		}
+		return u, nil
+	}
+	if cfg.Domain == "" {
+		return "", fmt.Errorf("config is missing 'domain' (or '%s')", field)
+	}
+	return "https://" + server + "." + cfg.Domain, nil
+}
+
+// checks a base URL, returning it without any trailing slash
+func parseBaseURL(s string) (string, error) {
+	u, err := url.Parse(s)
+	if err != nil {
+		return "", err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return "", fmt.Errorf("'%s' must start with http:// or https://", s)
+	}
+	if u.Host == "" {
+		return "", fmt.Errorf("'%s' has no host", s)
+	}
+	if u.RawQuery != "" || u.Fragment != "" || u.User != nil {
+		return "", fmt.Errorf("'%s' must not have credentials, a query or a fragment", s)
+	}
+	return strings.TrimRight(s, "/"), nil
+}
+
+// checks that the named fields are set
+func (cfg *Config) require(fields ...string) error {
+	values := map[string]string{
+		"auth":       cfg.Auth,
+		"planet_key": cfg.PlanetKey,
+		"stac_url":   cfg.STACURL,
+		"local_dir":  cfg.LocalDir,
+	}
+	for _, field := range fields {
+		if values[field] == "" {
+			return fmt.Errorf("config is missing '%s'", field)
+		}
+	}
+	return nil
+}
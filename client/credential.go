@@ -0,0 +1,100 @@
+/* Copyright 2017, RadiantBlue Technologies, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// Credential adds a key to each request made to a service, so that the
+// key never has to be part of a URL the caller sees.
+type Credential interface {
+	Apply(req *http.Request)
+}
+
+// HeaderCredential sends the key in a header, as "Authorization: api-key
+// <key>" unless another header or prefix is given. The header's value is
+// redacted wherever headers are logged.
+type HeaderCredential struct {
+	Header string // "Authorization" if empty
+	Prefix string // written before the key, e.g. "api-key "
+	Key    string
+}
+
+func (c *HeaderCredential) Apply(req *http.Request) {
+	header := c.Header
+	if header == "" {
+		header = "Authorization"
+	}
+	addSecretHeader(header)
+	req.Header.Set(header, c.Prefix+c.Key)
+}
+
+// BasicAuthCredential sends the key as the basic auth user name, with an
+// empty password, as Planet does.
+type BasicAuthCredential struct {
+	Key string
+}
+
+func (c *BasicAuthCredential) Apply(req *http.Request) {
+	req.SetBasicAuth(c.Key, "")
+}
+
+// QueryCredential adds the key to the URL as a query parameter, as the
+// broker has always accepted. The parameter is masked in logs and errors.
+type QueryCredential struct {
+	Param string // "PL_API_KEY" if empty
+	Key   string
+}
+
+func (c *QueryCredential) Apply(req *http.Request) {
+	params := req.URL.Query()
+	params.Set(c.param(), c.Key)
+	req.URL.RawQuery = params.Encode()
+}
+
+func (c *QueryCredential) param() string {
+	if c.Param == "" {
+		return planetKeyParam
+	}
+	return c.Param
+}
+
+// the broker's query parameter for the Planet key
+const planetKeyParam = "PL_API_KEY"
+
+// ways of sending the Planet key, as named by the catalog_auth setting
+const (
+	CatalogAuthHeader = "header"
+	CatalogAuthBasic  = "basic"
+	CatalogAuthQuery  = "query"
+)
+
+// returns the credential for sending the Planet key the named way,
+// "header" by default, keeping the key out of URLs; "query" is for a
+// broker that takes only the PL_API_KEY parameter
+func planetCredential(how string, key string) (Credential, error) {
+	switch how {
+	case CatalogAuthHeader, "":
+		return &HeaderCredential{Prefix: "api-key ", Key: key}, nil
+	case CatalogAuthBasic:
+		return &BasicAuthCredential{Key: key}, nil
+	case CatalogAuthQuery:
+		return &QueryCredential{Key: key}, nil
+	}
+	return nil, fmt.Errorf("catalog_auth must be header, basic or query: '%s'", how)
+}
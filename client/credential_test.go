@@ -0,0 +1,119 @@
+/* Copyright 2017, RadiantBlue Technologies, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCatalogCredential(t *testing.T) {
+	assert := assert.New(t)
+
+	var seen *http.Request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = r
+		w.Write([]byte(`{"type": "FeatureCollection", "features": []}`))
+	}))
+	defer server.Close()
+
+	cfg := &Config{PlanetKey: "secret-key", BrokerURL: server.URL, HTTPClient: server.Client()}
+
+	// by default, in a header, out of the URL
+	c, err := newCatalogClient(cfg)
+	assert.NoError(err)
+	_, err = c.SearchCatalogContext(context.Background(), "landsat", &SearchQuery{MaxCloudCover: CloudCover(10)})
+	assert.NoError(err)
+	assert.Equal("api-key secret-key", seen.Header.Get("Authorization"))
+	assert.Equal("", seen.URL.Query().Get("PL_API_KEY"))
+	assert.Equal("10", seen.URL.Query().Get("cloudCover"))
+
+	cfg.CatalogAuth = CatalogAuthBasic
+	c, err = newCatalogClient(cfg)
+	assert.NoError(err)
+	_, err = c.GetInfoForCatalogContext(context.Background(), "landsat")
+	assert.NoError(err)
+	user, _, ok := seen.BasicAuth()
+	assert.True(ok)
+	assert.Equal("secret-key", user)
+	assert.Equal("", seen.URL.RawQuery)
+
+	// in the URL only if asked
+	cfg.CatalogAuth = CatalogAuthQuery
+	c, err = newCatalogClient(cfg)
+	assert.NoError(err)
+	_, err = c.GetInfoForCatalogContext(context.Background(), "landsat")
+	assert.NoError(err)
+	assert.Equal("secret-key", seen.URL.Query().Get("PL_API_KEY"))
+	assert.Equal("", seen.Header.Get("Authorization"))
+
+	// a credential of one's own, needing no key
+	cfg = &Config{BrokerURL: server.URL, HTTPClient: server.Client(),
+		CatalogCredential: &HeaderCredential{Header: "X-Api-Key", Key: "other"}}
+	c, err = newCatalogClient(cfg)
+	assert.NoError(err)
+	_, err = c.GetInfoForCatalogContext(context.Background(), "landsat")
+	assert.NoError(err)
+	assert.Equal("other", seen.Header.Get("X-Api-Key"))
+
+	_, err = newCatalogClient(&Config{PlanetKey: "k", BrokerURL: server.URL, CatalogAuth: "cookie"})
+	assert.Error(err)
+}
+
+func TestCredentialKeptOutOfErrors(t *testing.T) {
+	assert := assert.New(t)
+
+	server := httptest.NewServer(http.NotFoundHandler())
+	brokerURL := server.URL
+	server.Close()
+
+	c, err := newCatalogClient(&Config{
+		PlanetKey:   "secret-key",
+		BrokerURL:   brokerURL,
+		CatalogAuth: CatalogAuthQuery,
+		HTTPClient:  &http.Client{},
+	})
+	assert.NoError(err)
+
+	_, err = c.GetInfoForCatalogContext(WithRetryPolicy(context.Background(), &RetryPolicy{MaxAttempts: 1}), "landsat")
+	assert.Error(err)
+	assert.NotContains(err.Error(), "secret-key")
+	assert.Contains(err.Error(), "PL_API_KEY=****")
+}
+
+func TestCredentialHeaderRedacted(t *testing.T) {
+	assert := assert.New(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"type": "FeatureCollection", "features": []}`))
+	}))
+	defer server.Close()
+
+	// a header of one's own is as secret as Authorization
+	c, err := newCatalogClient(&Config{BrokerURL: server.URL,
+		CatalogCredential: &HeaderCredential{Header: "x-planet-token", Prefix: "token ", Key: "secret-key"}})
+	assert.NoError(err)
+
+	buf := withLogLevel(t, LogDebug)
+	_, err = c.GetInfoForCatalogContext(context.Background(), "landsat")
+	assert.NoError(err)
+	assert.Contains(buf.String(), "> X-Planet-Token: token ****")
+	assert.NotContains(buf.String(), "secret-key")
+}
@@ -0,0 +1,261 @@
+/* Copyright 2017, RadiantBlue Technologies, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultDownloadWorkers = 4
+
+// how often a download in progress is reported
+const progressInterval = 250 * time.Millisecond
+
+// ExistingPolicy says what a download does about a file that is already
+// there.
+type ExistingPolicy string
+
+const (
+	ExistingFail      ExistingPolicy = "fail" // the default: download nothing
+	ExistingSkip      ExistingPolicy = "skip"
+	ExistingOverwrite ExistingPolicy = "overwrite"
+)
+
+// default file name templates
+const (
+	SceneTemplate     = "{file}"
+	CoastlineTemplate = "{job}.geojson"
+)
+
+// DownloadOptions controls how scenes and coastlines are downloaded.
+type DownloadOptions struct {
+	Workers  int              // number of bands downloaded at once, defaults to 4
+	Progress ProgressReporter // optional
+
+	// Files are written to OutputDir (default: the working directory), under
+	// a name made from Template, which may contain directories. Scene
+	// templates may use {catalog}, {scene}, {band} and {file} (the name the
+	// server gives the band); coastline templates may use {job}.
+	OutputDir string
+	Template  string
+	Existing  ExistingPolicy
+}
+
+// outputPath expands the template and places the result under the output
+// directory. The expanded name may not escape the directory.
+func (o *DownloadOptions) outputPath(defaultTemplate string, vars map[string]string) (string, error) {
+	template := o.Template
+	if template == "" {
+		template = defaultTemplate
+	}
+
+	name, err := expandTemplate(template, vars)
+	if err != nil {
+		return "", err
+	}
+
+	name = filepath.Clean(filepath.FromSlash(name))
+	if name == "." || filepath.IsAbs(name) || name == ".." || strings.HasPrefix(name, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("file name template '%s' must give a path inside the output directory, not '%s'", template, name)
+	}
+
+	dir := o.OutputDir
+	if dir == "" {
+		dir = "."
+	}
+
+	return filepath.Join(dir, name), nil
+}
+
+func (o *DownloadOptions) validate() error {
+	switch o.Existing {
+	case "", ExistingFail, ExistingSkip, ExistingOverwrite:
+		return nil
+	}
+	return fmt.Errorf("unknown policy for existing files: '%s'", o.Existing)
+}
+
+// returns the size of the file, or -1 if there is no file
+func existingSize(path string) (int64, error) {
+	stat, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return -1, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return stat.Size(), nil
+}
+
+// applies the policy to a file already at path: the result of skipping it,
+// an error if it may not be replaced, or neither if it is to be written
+func checkExisting(path string, policy ExistingPolicy) (*DownloadResult, error) {
+	size, err := existingSize(path)
+	if err != nil || size < 0 {
+		return nil, err
+	}
+	switch policy {
+	case ExistingSkip:
+		return &DownloadResult{File: path, Bytes: size, Skipped: true}, nil
+	case ExistingFail, "":
+		return nil, fmt.Errorf("file already exists, use skip or overwrite: %s", path)
+	}
+	return nil, nil
+}
+
+// makes the directories for the file
+func prepareOutputPath(path string) error {
+	return os.MkdirAll(filepath.Dir(path), 0755)
+}
+
+// "{scene}/{band}.TIF" -> "LC81260322017212LGN00/coastal.TIF"
+func expandTemplate(template string, vars map[string]string) (string, error) {
+	result := ""
+	for {
+		start := strings.Index(template, "{")
+		if start == -1 {
+			break
+		}
+		end := strings.Index(template[start:], "}")
+		if end == -1 {
+			return "", fmt.Errorf("unterminated '{' in file name template")
+		}
+		end += start
+
+		name := template[start+1 : end]
+		value, ok := vars[name]
+		if !ok {
+			return "", fmt.Errorf("unknown file name template variable '{%s}'", name)
+		}
+		if strings.ContainsAny(value, "/\\") || value == ".." {
+			return "", fmt.Errorf("template variable '{%s}' has an unsafe value: '%s'", name, value)
+		}
+
+		result += template[:start] + value
+		template = template[end+1:]
+	}
+	return result + template, nil
+}
+
+// DownloadResult describes one file of a download.
+type DownloadResult struct {
+	File    string // the path written
+	Band    string // for scene downloads
+	Bytes   int64
+	Skipped bool // the file already existed and was left alone
+}
+
+func (r *DownloadResult) String() string {
+	if r.Skipped {
+		return fmt.Sprintf("%s: %d bytes (already exists, skipped)", r.File, r.Bytes)
+	}
+	return fmt.Sprintf("%s: %d bytes", r.File, r.Bytes)
+}
+
+//---------------------------------------------------------------------
+
+// Progress is the state of one file download.
+type Progress struct {
+	File     string
+	Done     int64   // bytes written so far, including any resumed part
+	Total    int64   // expected size, or -1 if the server did not say
+	Rate     float64 // bytes per second transferred by this attempt
+	Finished bool    // set on the last report for the file
+	Err      error   // set if the download failed
+}
+
+// ProgressReporter receives progress reports during downloads. Reports for
+// different files may arrive concurrently.
+type ProgressReporter interface {
+	Report(p *Progress)
+}
+
+// ProgressFunc lets an ordinary function be used as a ProgressReporter.
+type ProgressFunc func(p *Progress)
+
+func (f ProgressFunc) Report(p *Progress) {
+	f(p)
+}
+
+//---------------------------------------------------------------------
+
+// an io.Writer that counts what passes through it, reporting at most once
+// per progressInterval
+type progressWriter struct {
+	reporter ProgressReporter
+	file     string
+	offset   int64 // bytes already present before this attempt
+	total    int64
+	written  int64
+	start    time.Time
+	last     time.Time
+	mutex    sync.Mutex
+}
+
+func newProgressWriter(reporter ProgressReporter, file string, offset int64, total int64) *progressWriter {
+	now := time.Now()
+	return &progressWriter{
+		reporter: reporter,
+		file:     file,
+		offset:   offset,
+		total:    total,
+		start:    now,
+		last:     now,
+	}
+}
+
+func (w *progressWriter) Write(p []byte) (int, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	w.written += int64(len(p))
+
+	now := time.Now()
+	if now.Sub(w.last) >= progressInterval {
+		w.last = now
+		w.reporter.Report(w.progress(now, false, nil))
+	}
+
+	return len(p), nil
+}
+
+// sends the final report for the file
+func (w *progressWriter) finish(err error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	w.reporter.Report(w.progress(time.Now(), true, err))
+}
+
+func (w *progressWriter) progress(now time.Time, finished bool, err error) *Progress {
+	rate := 0.0
+	if elapsed := now.Sub(w.start).Seconds(); elapsed > 0 {
+		rate = float64(w.written) / elapsed
+	}
+	return &Progress{
+		File:     w.file,
+		Done:     w.offset + w.written,
+		Total:    w.total,
+		Rate:     rate,
+		Finished: finished,
+		Err:      err,
+	}
+}
@@ -0,0 +1,69 @@
+/* Copyright 2017, RadiantBlue Technologies, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpandTemplate(t *testing.T) {
+	assert := assert.New(t)
+
+	vars := map[string]string{"catalog": "landsat", "scene": "LC81260322017212LGN00", "band": "coastal"}
+
+	s, err := expandTemplate("{catalog}/{scene}/{band}.TIF", vars)
+	assert.NoError(err)
+	assert.Equal("landsat/LC81260322017212LGN00/coastal.TIF", s)
+
+	s, err = expandTemplate("plain.TIF", vars)
+	assert.NoError(err)
+	assert.Equal("plain.TIF", s)
+
+	_, err = expandTemplate("{nope}.TIF", vars)
+	assert.Error(err)
+
+	_, err = expandTemplate("{scene", vars)
+	assert.Error(err)
+
+	_, err = expandTemplate("{band}", map[string]string{"band": "../x"})
+	assert.Error(err)
+}
+
+func TestDownloadOutputPath(t *testing.T) {
+	assert := assert.New(t)
+
+	vars := map[string]string{"job": "6f475d16"}
+
+	opts := &DownloadOptions{}
+	s, err := opts.outputPath(CoastlineTemplate, vars)
+	assert.NoError(err)
+	assert.Equal("6f475d16.geojson", s)
+
+	opts = &DownloadOptions{OutputDir: "/tmp/out", Template: "coast/{job}.json"}
+	s, err = opts.outputPath(CoastlineTemplate, vars)
+	assert.NoError(err)
+	assert.Equal(filepath.FromSlash("/tmp/out/coast/6f475d16.json"), s)
+
+	opts = &DownloadOptions{Template: "../{job}.json"}
+	_, err = opts.outputPath(CoastlineTemplate, vars)
+	assert.Error(err)
+
+	opts = &DownloadOptions{Existing: "sometimes"}
+	assert.Error(opts.validate())
+}
@@ -0,0 +1,137 @@
+/* Copyright 2017, RadiantBlue Technologies, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Kinds of API failure, for use with errors.Is:
+//
+//	if errors.Is(err, client.ErrNotFound) { ... }
+var (
+	ErrUnauthorized = errors.New("not authorized") // 401 or 403
+	ErrNotFound     = errors.New("not found")      // 404
+	ErrRateLimited  = errors.New("rate limited")   // 429
+	ErrServer       = errors.New("server error")   // 5xx
+)
+
+// how much of a failed response's body is kept
+const maxErrorBody = 4096
+
+// APIError is a response with an unexpected status. Use errors.As to get
+// at it, or errors.Is with ErrUnauthorized, ErrNotFound, ErrRateLimited
+// or ErrServer to tell the common cases apart.
+type APIError struct {
+	Method     string
+	URL        string // with any secrets redacted
+	StatusCode int
+	Message    string // the server's explanation, if it gave one
+	Body       string // the start of the response body
+}
+
+func (e *APIError) Error() string {
+	s := fmt.Sprintf("%s %s: %d %s", e.Method, e.URL, e.StatusCode, http.StatusText(e.StatusCode))
+	if e.Message != "" {
+		s += ": " + e.Message
+	}
+	return s
+}
+
+// Is reports whether the error is of the given kind.
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests
+	case ErrServer:
+		return e.StatusCode >= 500
+	}
+	return false
+}
+
+// builds the error from the response, reading and closing its body
+func newAPIError(resp *http.Response) *APIError {
+	byts, _ := ioutil.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+	resp.Body.Close()
+
+	e := &APIError{
+		StatusCode: resp.StatusCode,
+		Body:       string(byts),
+		Message:    errorMessage(byts),
+	}
+	if resp.Request != nil {
+		e.Method = resp.Request.Method
+		e.URL = redactURL(resp.Request.URL)
+	}
+	return e
+}
+
+// finds the explanation in an error body: {"error": "..."},
+// {"error": {"message": "..."}}, {"message": "..."}, {"detail": "..."} or
+// STAC's {"description": "..."}, or else a short plain text body
+func errorMessage(body []byte) string {
+	obj := map[string]interface{}{}
+	if json.Unmarshal(body, &obj) == nil {
+		if nested, ok := obj["error"].(map[string]interface{}); ok {
+			obj = nested
+		}
+		for _, key := range []string{"message", "error", "detail", "description"} {
+			if s, ok := obj[key].(string); ok && s != "" {
+				return s
+			}
+		}
+		return ""
+	}
+
+	text := strings.TrimSpace(string(body))
+	if text == "" || strings.HasPrefix(text, "<") || strings.Contains(text, "\n") || len(text) > 200 {
+		return ""
+	}
+	return text
+}
+
+// query parameters that carry keys
+var secretParams = []string{"PL_API_KEY", "api_key"}
+
+// returns the URL without any user info, and with secret query
+// parameters masked
+func redactURL(u *url.URL) string {
+	r := *u
+	r.User = nil
+	params := r.Query()
+	changed := false
+	for _, name := range secretParams {
+		if params.Get(name) != "" {
+			params.Set(name, "****")
+			changed = true
+		}
+	}
+	if changed {
+		r.RawQuery = strings.Replace(params.Encode(), "%2A%2A%2A%2A", "****", -1)
+	}
+	return r.String()
+}
@@ -0,0 +1,83 @@
+/* Copyright 2017, RadiantBlue Technologies, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAPIError(t *testing.T) {
+	assert := assert.New(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v0/job/missing":
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error": {"message": "job not found"}}`))
+		case "/v0/job/forbidden":
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte("bad key"))
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("<html><body>oops</body></html>"))
+		}
+	}))
+	defer server.Close()
+
+	c := &JobClient{url: server.URL, auth: "abc", httpClient: server.Client()}
+
+	_, err := c.GetInfoForJob("missing")
+	apiErr := &APIError{}
+	assert.True(errors.As(err, &apiErr))
+	assert.Equal("GET", apiErr.Method)
+	assert.Equal(server.URL+"/v0/job/missing", apiErr.URL)
+	assert.Equal(404, apiErr.StatusCode)
+	assert.Equal("job not found", apiErr.Message)
+	assert.True(errors.Is(err, ErrNotFound))
+	assert.False(errors.Is(err, ErrServer))
+	assert.Equal("GET "+server.URL+"/v0/job/missing: 404 Not Found: job not found", err.Error())
+
+	err = c.DoJobDelete("forbidden")
+	assert.True(errors.Is(err, ErrUnauthorized))
+	assert.True(errors.As(err, &apiErr))
+	assert.Equal("DELETE", apiErr.Method)
+	assert.Equal("bad key", apiErr.Message)
+
+	_, err = c.GetInfoForJobsContext(context.Background())
+	assert.True(errors.Is(err, ErrServer))
+	assert.True(errors.As(err, &apiErr))
+	assert.Equal("", apiErr.Message)
+	assert.Contains(apiErr.Body, "oops")
+}
+
+func TestRedactURL(t *testing.T) {
+	assert := assert.New(t)
+
+	u, err := url.Parse("https://user:pw@broker.example.com/planet/discover/landsat?PL_API_KEY=secret&cloudCover=10")
+	assert.NoError(err)
+	assert.Equal("https://broker.example.com/planet/discover/landsat?PL_API_KEY=****&cloudCover=10", redactURL(u))
+
+	u, err = url.Parse("https://bf-api.example.com/v0/job?x=1")
+	assert.NoError(err)
+	assert.Equal("https://bf-api.example.com/v0/job?x=1", redactURL(u))
+}
@@ -16,76 +16,500 @@ limitations under the License.
 package client
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"net/http"
+	"strings"
+	"time"
 
-	"gopkg.in/urfave/cli.v1"
+	"github.com/venicegeo/bf-client/geojson"
 )
 
 type JobClient struct {
-	url  string
-	auth string
+	url        string
+	auth       string
+	httpClient *http.Client
 }
 
 func NewJobClient() (*JobClient, error) {
+	settings, err := LoadSettings()
+	if err != nil {
+		return nil, err
+	}
+	return NewJobClientWithSettings(settings)
+}
 
-	fields, err := ReadBeachfrontrcFields([]string{"domain", "auth"})
+// NewJobClientWithSettings builds a job client from settings the caller
+// has already loaded, for when it needs others of them too.
+func NewJobClientWithSettings(settings *Settings) (*JobClient, error) {
+	cfg, err := requireConfig(settings, []string{"auth"}, "api_url")
 	if err != nil {
 		return nil, err
 	}
+	return newJobClient(cfg.withHTTPClient())
+}
 
-	url := "https://" + apiServer + "." + fields["domain"]
+func newJobClient(cfg *Config) (*JobClient, error) {
+
+	err := cfg.require("auth")
+	if err != nil {
+		return nil, err
+	}
+
+	url, err := cfg.serviceURL(cfg.APIURL, "api_url", apiServer)
+	if err != nil {
+		return nil, err
+	}
 
 	return &JobClient{
-		url:  url,
-		auth: fields["auth"],
+		url:        url,
+		auth:       cfg.Auth,
+		httpClient: cfg.HTTPClient,
 	}, nil
 }
 
 //---------------------------------------------------------------------
 
-func (c *JobClient) GetInfoForJobs() (string, error) {
+// JobSubmission holds the parameters of a new job request.
+type JobSubmission struct {
+	Name        string `json:"name"`
+	AlgorithmId string `json:"algorithm_id"`
+	SceneId     string `json:"scene_id"` // "landsat:LC80480102017209LGN00"
+	PlanetKey   string `json:"planet_api_key"`
+	ComputeMask bool   `json:"compute_mask"`
+}
+
+func (s *JobSubmission) validate() error {
+	_, _, err := splitId(s.SceneId)
+	if err != nil {
+		return err
+	}
+	if s.AlgorithmId == "" {
+		return fmt.Errorf("job submission requires an algorithm id")
+	}
+	if s.Name == "" {
+		return fmt.Errorf("job submission requires a job name")
+	}
+	if s.PlanetKey == "" {
+		return fmt.Errorf("job submission requires a Planet API key")
+	}
+	return nil
+}
+
+// job statuses reported by the bf-api
+const (
+	JobStatusSubmitted = "Submitted"
+	JobStatusPending   = "Pending"
+	JobStatusRunning   = "Running"
+	JobStatusSuccess   = "Success"
+	JobStatusError     = "Error"
+	JobStatusTimedOut  = "Timed Out"
+)
+
+// JobList is the user's job list, a GeoJSON FeatureCollection of jobs.
+type JobList struct {
+	Type     string
+	Features []*Job
+}
 
-	log.Printf("Job.GetInfoForJobs")
+func (l *JobList) String() string {
+	s := ""
+	for _, v := range l.Features {
+		s += v.String() + "\n"
+	}
+	return s
+}
+
+// Job is a single job, a GeoJSON Feature whose geometry is the footprint
+// of the job's scene.
+type Job struct {
+	Type       string
+	Id         string
+	Geometry   *geojson.Geometry
+	Properties *JobProperties
+}
+
+type JobProperties struct {
+	Name             string
+	Status           string
+	CreatedBy        string `json:"created_by"`
+	CreatedOn        string `json:"created_on"`
+	AlgorithmName    string `json:"algorithm_name"`
+	AlgorithmVersion string `json:"algorithm_version"`
+	SceneId          string `json:"scene_id"`
+	SceneCaptureDate string `json:"scene_capture_date"`
+	SceneSensorName  string `json:"scene_sensor_name"`
+	ErrorMessage     string `json:"error_message"`  // set only when Status is "Error"
+	ExecutionStep    string `json:"execution_step"` // where the error occurred
+}
+
+func (j *Job) String() string {
+	p := j.Properties
+	s := fmt.Sprintf("[job %s] %s\n", j.Id, p.Name)
+	s += fmt.Sprintf("    status:    %s\n", p.Status)
+	s += fmt.Sprintf("    created:   %s\n", p.CreatedOn)
+	s += fmt.Sprintf("    algorithm: %s %s\n", p.AlgorithmName, p.AlgorithmVersion)
+	s += fmt.Sprintf("    scene:     %s\n", p.SceneId)
+	if p.ErrorMessage != "" {
+		s += fmt.Sprintf("    error:     %s (%s)\n", p.ErrorMessage, p.ExecutionStep)
+	}
+	return s
+}
+
+// IsFinished returns true once the job can no longer change status.
+func (j *Job) IsFinished() bool {
+	switch j.Properties.Status {
+	case JobStatusSuccess, JobStatusError, JobStatusTimedOut:
+		return true
+	}
+	return false
+}
+
+// the bf-api wraps a single job as {"job": {...}}
+type jobResponse struct {
+	Job *Job
+}
+
+// the bf-api wraps the job list as {"jobs": {"type": "FeatureCollection", "features": [...]}}
+type jobsResponse struct {
+	Jobs *JobList
+}
+
+// the bf-api writes timestamps as ISO 8601, with or without a zone
+var createdOnLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999",
+}
+
+// CreatedOnTime parses the job's creation timestamp.
+func (j *Job) CreatedOnTime() (time.Time, error) {
+	for _, layout := range createdOnLayouts {
+		t, err := time.Parse(layout, j.Properties.CreatedOn)
+		if err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unable to parse creation time of job %s: '%s'", j.Id, j.Properties.CreatedOn)
+}
+
+// JobFilter selects jobs from the user's job list. Empty fields match
+// everything.
+type JobFilter struct {
+	Status    string        // "Error", "Success", ... (case-insensitive)
+	OlderThan time.Duration // created at least this long ago
+}
+
+func (f *JobFilter) matches(job *Job, now time.Time) (bool, error) {
+	if f.Status != "" && !strings.EqualFold(f.Status, job.Properties.Status) {
+		return false, nil
+	}
+	if f.OlderThan > 0 {
+		created, err := job.CreatedOnTime()
+		if err != nil {
+			return false, err
+		}
+		if now.Sub(created) < f.OlderThan {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+//---------------------------------------------------------------------
+
+func (c *JobClient) GetInfoForJobs() (*JobList, error) {
+	return c.GetInfoForJobsContext(context.Background())
+}
+
+// GetInfoForJobsContext is GetInfoForJobs with a context, for cancellation and deadlines.
+func (c *JobClient) GetInfoForJobsContext(ctx context.Context) (*JobList, error) {
+
+	debugf("Job.GetInfoForJobs")
 
 	path := "/v0/job"
 	url := fmt.Sprintf("%s%s", c.url, path)
 
-	responseBody, err := doHttpGetJSONWithAuth(url, c.auth, 200)
+	responseBody, err := doHttpGetJSONWithAuth(ctx, c.httpClient, url, c.auth, 200)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	//log.Print(responseBody)
+	obj := &jobsResponse{}
+	err = json.Unmarshal([]byte(responseBody), obj)
+	if err != nil {
+		return nil, err
+	}
+	if obj.Jobs == nil {
+		return nil, fmt.Errorf("job list response contains no jobs")
+	}
+
+	return obj.Jobs, nil
+}
 
-	return responseBody, nil
+func (c *JobClient) GetInfoForJob(id string) (*Job, error) {
+	return c.GetInfoForJobContext(context.Background(), id)
 }
 
-func (c *JobClient) GetInfoForJob(id string) (string, error) {
+// GetInfoForJobContext is GetInfoForJob with a context, for cancellation and deadlines.
+func (c *JobClient) GetInfoForJobContext(ctx context.Context, id string) (*Job, error) {
 
-	log.Printf("Job.GetInfoForJob")
+	debugf("Job.GetInfoForJob")
 
 	path := "/v0/job"
 	url := fmt.Sprintf("%s%s/%s", c.url, path, id)
 
-	responseBody, err := doHttpGetJSONWithAuth(url, c.auth, 200)
+	responseBody, err := doHttpGetJSONWithAuth(ctx, c.httpClient, url, c.auth, 200)
+	if err != nil {
+		return nil, err
+	}
+
+	obj := &jobResponse{}
+	err = json.Unmarshal([]byte(responseBody), obj)
+	if err != nil {
+		return nil, err
+	}
+	if obj.Job == nil || obj.Job.Properties == nil {
+		return nil, fmt.Errorf("job response contains no job")
+	}
+
+	return obj.Job, nil
+}
+
+func (c *JobClient) DoJobSubmit(submission *JobSubmission) (*Job, error) {
+	return c.DoJobSubmitContext(context.Background(), submission)
+}
+
+// DoJobSubmitContext is DoJobSubmit with a context, for cancellation and deadlines.
+func (c *JobClient) DoJobSubmitContext(ctx context.Context, submission *JobSubmission) (*Job, error) {
+	debugf("Job.DoJobSubmit")
+
+	err := submission.validate()
+	if err != nil {
+		return nil, err
+	}
+
+	byts, err := json.Marshal(submission)
+	if err != nil {
+		return nil, err
+	}
+
+	path := "/v0/job"
+	url := fmt.Sprintf("%s%s", c.url, path)
+
+	responseBody, err := doHttpPostJSONWithAuth(ctx, c.httpClient, url, c.auth, string(byts), 201)
+	if err != nil {
+		return nil, err
+	}
+
+	obj := &jobResponse{}
+	err = json.Unmarshal([]byte(responseBody), obj)
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+	if obj.Job == nil || obj.Job.Properties == nil {
+		return nil, fmt.Errorf("job submission returned no job")
 	}
 
-	//log.Print(responseBody)
+	return obj.Job, nil
+}
 
-	return responseBody, nil
+// FindJobs returns the jobs in the user's job list that match the filter.
+func (c *JobClient) FindJobs(filter *JobFilter) ([]*Job, error) {
+	return c.FindJobsContext(context.Background(), filter)
 }
 
-func (c *JobClient) DoJobSubmit() error {
-	log.Printf("Job.DoJobSubmit")
+// FindJobsContext is FindJobs with a context, for cancellation and deadlines.
+func (c *JobClient) FindJobsContext(ctx context.Context, filter *JobFilter) ([]*Job, error) {
+	debugf("Job.FindJobs")
 
-	return cli.NewExitError("job: --submit not yet supported", 2)
+	return findJobs(ctx, c, filter)
 }
 
+// the jobs in the service's job list that match the filter. A job whose
+// age the filter needs but cannot be told is left out, with a warning.
+func findJobs(ctx context.Context, service JobService, filter *JobFilter) ([]*Job, error) {
+	list, err := service.GetInfoForJobsContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	jobs := []*Job{}
+	for _, job := range list.Features {
+		ok, err := filter.matches(job, now)
+		if err != nil {
+			warnf("skipping job %s: %s", job.Id, err.Error())
+			continue
+		}
+		if ok {
+			jobs = append(jobs, job)
+		}
+	}
+
+	return jobs, nil
+}
+
+// DoJobDelete removes the job from the user's job list.
 func (c *JobClient) DoJobDelete(id string) error {
-	log.Printf("Job.DoJobDelete")
+	return c.DoJobDeleteContext(context.Background(), id)
+}
+
+// DoJobDeleteContext is DoJobDelete with a context, for cancellation and deadlines.
+func (c *JobClient) DoJobDeleteContext(ctx context.Context, id string) error {
+	debugf("Job.DoJobDelete")
+
+	if id == "" {
+		return fmt.Errorf("job deletion requires a job id")
+	}
 
-	return cli.NewExitError("job: --delete not yet supported", 2)
+	path := "/v0/job"
+	url := fmt.Sprintf("%s%s/%s", c.url, path, id)
+
+	_, err := doHttpDeleteWithAuth(ctx, c.httpClient, url, c.auth, 200)
+	if err != nil {
+		return fmt.Errorf("unable to delete job %s: %w", id, err)
+	}
+
+	return nil
+}
+
+// DoJobDeleteMany deletes each of the jobs, continuing past failures. It
+// returns the ids that were deleted and an error naming the ones that were
+// not.
+func (c *JobClient) DoJobDeleteMany(ids []string) ([]string, error) {
+	return c.DoJobDeleteManyContext(context.Background(), ids)
+}
+
+// DoJobDeleteManyContext is DoJobDeleteMany with a context, for cancellation and deadlines.
+func (c *JobClient) DoJobDeleteManyContext(ctx context.Context, ids []string) ([]string, error) {
+	debugf("Job.DoJobDeleteMany")
+
+	return deleteJobs(ctx, c, ids)
+}
+
+// deletes each of the jobs from the service, as DoJobDeleteMany does
+func deleteJobs(ctx context.Context, service JobService, ids []string) ([]string, error) {
+	deleted := []string{}
+	failed := []string{}
+	var firstErr error
+
+	for _, id := range ids {
+		if ctx.Err() != nil {
+			return deleted, ctx.Err()
+		}
+		err := service.DoJobDeleteContext(ctx, id)
+		if err != nil {
+			verbosef("%s", err.Error())
+			failed = append(failed, id)
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		deleted = append(deleted, id)
+	}
+
+	if len(failed) > 0 {
+		return deleted, fmt.Errorf("unable to delete %d of %d jobs: %s (first error: %w)",
+			len(failed), len(ids), strings.Join(failed, ", "), firstErr)
+	}
+
+	return deleted, nil
+}
+
+//---------------------------------------------------------------------
+
+// ErrWaitTimeout is returned by WaitForJob when the job has not finished
+// within the timeout.
+var ErrWaitTimeout = errors.New("timed out waiting for job")
+
+const defaultWaitInterval = 10 * time.Second
+
+// WaitOptions controls the polling done by WaitForJob.
+type WaitOptions struct {
+	Interval time.Duration  // time between polls, defaults to 10s
+	Timeout  time.Duration  // overall time limit, polls in progress included; zero means wait forever
+	OnStatus func(job *Job) // called for the first status and for every change
+}
+
+// WaitForJob polls the job until it is finished, returning its final state.
+// A job that finished with an error is not an error here: check the
+// returned job's status. On timeout the last state seen is returned along
+// with ErrWaitTimeout, and likewise with the context's error if it is
+// cancelled.
+func (c *JobClient) WaitForJob(id string, opts *WaitOptions) (*Job, error) {
+	return c.WaitForJobContext(context.Background(), id, opts)
+}
+
+// WaitForJobContext is WaitForJob with a context, for cancellation and deadlines.
+func (c *JobClient) WaitForJobContext(ctx context.Context, id string, opts *WaitOptions) (*Job, error) {
+	debugf("Job.WaitForJob")
+
+	return waitForJob(ctx, c, id, opts)
+}
+
+// polls the service for the job until it is finished, as WaitForJob does
+func waitForJob(ctx context.Context, service JobService, id string, opts *WaitOptions) (*Job, error) {
+	if opts == nil {
+		opts = &WaitOptions{}
+	}
+	interval := opts.Interval
+	if interval <= 0 {
+		interval = defaultWaitInterval
+	}
+
+	// the timeout bounds the polls in progress too, retries and all
+	pollCtx := ctx
+	var deadline time.Time
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		pollCtx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+		deadline, _ = pollCtx.Deadline()
+	}
+
+	var last *Job
+	status := ""
+	for {
+		job, err := service.GetInfoForJobContext(pollCtx, id)
+		if err != nil {
+			if ctx.Err() != nil {
+				return last, ctx.Err()
+			}
+			if pollCtx.Err() != nil {
+				return last, ErrWaitTimeout
+			}
+			return nil, err
+		}
+		last = job
+
+		if job.Properties.Status != status {
+			status = job.Properties.Status
+			if opts.OnStatus != nil {
+				opts.OnStatus(job)
+			}
+		}
+
+		if job.IsFinished() {
+			return job, nil
+		}
+
+		if !deadline.IsZero() {
+			remaining := deadline.Sub(time.Now())
+			if remaining <= 0 {
+				return job, ErrWaitTimeout
+			}
+			if remaining < interval {
+				interval = remaining
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return job, ctx.Err()
+		case <-time.After(interval):
+		}
+	}
 }
@@ -16,69 +16,285 @@ limitations under the License.
 package client
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/venicegeo/bf-client/bftest"
 )
 
 func TestJobInfoForJobs(t *testing.T) {
 	assert := assert.New(t)
 
-	c, err := NewJobClient()
+	c, _ := newTestClient(t)
+
+	jobs, err := c.Job.GetInfoForJobs()
 	assert.NoError(err)
 
-	s, err := c.GetInfoForJobs()
+	assert.Equal("FeatureCollection", jobs.Type)
+	assert.NotEmpty(jobs.Features)
+	assert.Equal("Feature", jobs.Features[0].Type)
+	assert.NotEqual("", jobs.Features[0].Id)
+}
+
+func TestJobInfoForJob(t *testing.T) {
+	assert := assert.New(t)
+
+	c, _ := newTestClient(t)
+
+	job, err := c.Job.GetInfoForJob(testJobId)
 	assert.NoError(err)
 
-	obj := map[string]interface{}{}
-	err = json.Unmarshal([]byte(s), &obj)
+	assert.Equal(testJobId, job.Id)
+	assert.NotEqual("", job.Properties.Status)
+
+	_, err = c.Job.GetInfoForJob("no-such-job")
+	assert.True(errors.Is(err, ErrNotFound))
+}
+
+func TestJobDecode(t *testing.T) {
+	assert := assert.New(t)
+
+	const jsn = `{"job": {
+		"type": "Feature",
+		"id": "6f475d16-d8f1-4f15-9dda-cf9b2d502241",
+		"geometry": {"type": "Polygon", "coordinates": [[[1,2],[3,4],[5,6],[1,2]]]},
+		"properties": {
+			"name": "test job",
+			"status": "Error",
+			"created_on": "2017-08-01T12:34:56.789Z",
+			"algorithm_name": "NDWI_PY",
+			"algorithm_version": "0.0",
+			"scene_id": "landsat:LC81260322017212LGN00",
+			"error_message": "no shoreline found",
+			"execution_step": "processing"
+		}
+	}}`
+
+	obj := &jobResponse{}
+	err := json.Unmarshal([]byte(jsn), obj)
 	assert.NoError(err)
 
-	obj2 := obj["jobs"].(map[string]interface{})
-	obj3 := obj2["features"].([]interface{})
-	obj4 := obj3[0].(map[string]interface{})
+	job := obj.Job
+	assert.Equal("6f475d16-d8f1-4f15-9dda-cf9b2d502241", job.Id)
+	assert.Equal("Polygon", job.Geometry.Type())
+	assert.Equal("test job", job.Properties.Name)
+	assert.Equal("Error", job.Properties.Status)
+	assert.Equal("NDWI_PY", job.Properties.AlgorithmName)
+	assert.Equal("landsat:LC81260322017212LGN00", job.Properties.SceneId)
+	assert.Equal("no shoreline found", job.Properties.ErrorMessage)
+
+	created, err := job.CreatedOnTime()
+	assert.NoError(err)
+	assert.Equal(2017, created.Year())
 
-	assert.Equal("Feature", obj4["type"])
-	assert.NotEqual("", obj4["id"])
+	assert.Contains(job.String(), "[job 6f475d16-d8f1-4f15-9dda-cf9b2d502241] test job")
+	assert.Contains(job.String(), "no shoreline found")
 }
 
-func TestJobInfoForJob(t *testing.T) {
+func TestJobSubmit(t *testing.T) {
 	assert := assert.New(t)
 
-	const id = "6f475d16-d8f1-4f15-9dda-cf9b2d502241"
+	c, server := newTestClient(t)
+
+	// scene id must be "<catalog>:<scene>"
+	_, err := c.Job.DoJobSubmit(&JobSubmission{
+		Name:        "test",
+		AlgorithmId: testAlgorithmId,
+		SceneId:     "LC81260322017212LGN00",
+		PlanetKey:   bftest.PlanetKey,
+	})
+	assert.Error(err)
 
-	c, err := NewJobClient()
+	job, err := c.Job.DoJobSubmit(&JobSubmission{
+		Name:        "test",
+		AlgorithmId: testAlgorithmId,
+		SceneId:     testSceneId,
+		PlanetKey:   bftest.PlanetKey,
+	})
 	assert.NoError(err)
+	assert.Equal(JobStatusSubmitted, job.Properties.Status)
+	assert.Equal("NDWI_PY", job.Properties.AlgorithmName)
+	assert.NotNil(server.Job(job.Id))
+
+	// the service checks the scene exists
+	_, err = c.Job.DoJobSubmit(&JobSubmission{
+		Name:        "test",
+		AlgorithmId: testAlgorithmId,
+		SceneId:     "landsat:LC80000000000000LGN00",
+		PlanetKey:   bftest.PlanetKey,
+	})
+	apiErr := &APIError{}
+	assert.True(errors.As(err, &apiErr))
+	assert.Equal(400, apiErr.StatusCode)
+	assert.Contains(apiErr.Message, "no such scene")
+}
+
+func TestJobDelete(t *testing.T) {
+	assert := assert.New(t)
 
-	s, err := c.GetInfoForJob(id)
+	c, server := newTestClient(t)
+
+	err := c.Job.DoJobDelete("")
+	assert.Error(err)
+
+	err = c.Job.DoJobDelete(testJobId)
 	assert.NoError(err)
+	assert.Nil(server.Job(testJobId))
+
+	err = c.Job.DoJobDelete(testJobId)
+	assert.True(errors.Is(err, ErrNotFound))
+}
+
+func TestJobInjectedFailures(t *testing.T) {
+	assert := assert.New(t)
+
+	c, server := newTestClient(t)
 
-	obj := map[string]interface{}{}
-	err = json.Unmarshal([]byte(s), &obj)
+	// retried past two failures...
+	server.Fail("/v0/job", http.StatusServiceUnavailable, 2)
+	ctx := WithRetryPolicy(context.Background(), &RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond})
+	_, err := c.Job.GetInfoForJobContext(ctx, testJobId)
 	assert.NoError(err)
+	assert.Equal([]string{"GET /v0/job/" + testJobId, "GET /v0/job/" + testJobId, "GET /v0/job/" + testJobId}, server.Requests())
 
-	obj2 := obj["job"].(map[string]interface{})
+	// ...but not past a refusal
+	server.Fail("/v0/job", http.StatusForbidden, -1)
+	_, err = c.Job.GetInfoForJobContext(ctx, testJobId)
+	assert.True(errors.Is(err, ErrUnauthorized))
+	server.ClearFailures()
 
-	assert.Equal(id, obj2["id"])
+	// a slow server runs into the deadline
+	server.SetLatency(time.Minute)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = c.Job.GetInfoForJobsContext(ctx)
+	assert.True(errors.Is(err, context.DeadlineExceeded))
 }
 
-func TestJobSubmit(t *testing.T) {
+func TestJobFilter(t *testing.T) {
 	assert := assert.New(t)
 
-	c, err := NewJobClient()
+	now := time.Date(2017, 8, 31, 12, 0, 0, 0, time.UTC)
+
+	oldFailed := &Job{Id: "1", Properties: &JobProperties{Status: "Error", CreatedOn: "2017-07-01T10:00:00.123456"}}
+	newFailed := &Job{Id: "2", Properties: &JobProperties{Status: "Error", CreatedOn: "2017-08-30T10:00:00Z"}}
+	oldSuccess := &Job{Id: "3", Properties: &JobProperties{Status: "Success", CreatedOn: "2017-07-01T10:00:00Z"}}
+
+	filter := &JobFilter{Status: "error", OlderThan: 30 * 24 * time.Hour}
+
+	ok, err := filter.matches(oldFailed, now)
+	assert.NoError(err)
+	assert.True(ok)
+
+	ok, err = filter.matches(newFailed, now)
+	assert.NoError(err)
+	assert.False(ok)
+
+	ok, err = filter.matches(oldSuccess, now)
 	assert.NoError(err)
+	assert.False(ok)
 
-	err = c.DoJobSubmit()
+	bad := &Job{Id: "4", Properties: &JobProperties{Status: "Error", CreatedOn: "yesterday"}}
+	_, err = filter.matches(bad, now)
 	assert.Error(err)
+
+	// finding jobs skips it, with a warning, and goes on
+	logged := withLogLevel(t, LogQuiet)
+	jobs := NewMemoryJobs()
+	recent := &Job{Id: "5", Properties: &JobProperties{Status: "Error", CreatedOn: time.Now().Format(time.RFC3339)}}
+	for _, job := range []*Job{bad, oldFailed, recent} {
+		jobs.AddJob(job)
+	}
+	found, err := jobs.FindJobs(filter)
+	assert.NoError(err)
+	assert.Len(found, 1)
+	assert.Equal("1", found[0].Id)
+	assert.Contains(logged.String(), "warning: skipping job 4")
 }
 
-func TestJobDelete(t *testing.T) {
+func TestJobWait(t *testing.T) {
 	assert := assert.New(t)
 
-	c, err := NewJobClient()
+	c, server := newTestClient(t)
+
+	id := server.AddJob(&bftest.Job{
+		Name:    "waiting",
+		SceneId: testSceneId,
+		Then:    []string{JobStatusRunning, JobStatusRunning, JobStatusSuccess},
+	})
+
+	statuses := []string{}
+	opts := &WaitOptions{
+		Interval: time.Millisecond,
+		Timeout:  time.Minute,
+		OnStatus: func(job *Job) { statuses = append(statuses, job.Properties.Status) },
+	}
+
+	job, err := c.Job.WaitForJob(id, opts)
 	assert.NoError(err)
+	assert.True(job.IsFinished())
+	assert.Equal(JobStatusSuccess, job.Properties.Status)
+	assert.Equal([]string{JobStatusSubmitted, JobStatusRunning, JobStatusSuccess}, statuses)
+}
 
-	err = c.DoJobDelete("")
-	assert.Error(err)
+func TestJobWaitCancel(t *testing.T) {
+	assert := assert.New(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"job": {"id": "1", "properties": {"status": "Running"}}}`))
+	}))
+	defer server.Close()
+
+	c := &JobClient{url: server.URL, auth: "abc", httpClient: server.Client()}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	job, err := c.WaitForJobContext(ctx, "1", &WaitOptions{Interval: time.Minute})
+	assert.Equal(context.DeadlineExceeded, err)
+	assert.Equal(JobStatusRunning, job.Properties.Status)
+	assert.True(time.Since(start) < 10*time.Second)
+}
+
+func TestJobWaitTimeoutBoundsPoll(t *testing.T) {
+	assert := assert.New(t)
+
+	c, server := newTestClient(t)
+	server.SetLatency(5 * time.Second)
+
+	start := time.Now()
+	_, err := c.Job.WaitForJob(testJobId, &WaitOptions{Timeout: 100 * time.Millisecond})
+	assert.Equal(ErrWaitTimeout, err)
+	assert.True(time.Since(start) < time.Second)
+}
+
+func TestJobWaitCancelledDuringPoll(t *testing.T) {
+	assert := assert.New(t)
+
+	c, server := newTestClient(t)
+	id := server.AddJob(&bftest.Job{Name: "j", Status: JobStatusRunning})
+
+	// the first poll answers at once; the second is cancelled while the
+	// server is still thinking about it
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	opts := &WaitOptions{Interval: 10 * time.Millisecond, OnStatus: func(job *Job) {
+		server.SetLatency(5 * time.Second)
+		time.AfterFunc(100*time.Millisecond, cancel)
+	}}
+
+	start := time.Now()
+	job, err := c.Job.WaitForJobContext(ctx, id, opts)
+	assert.Equal(context.Canceled, err)
+	assert.True(time.Since(start) < time.Second)
+	if assert.NotNil(job) {
+		assert.Equal(JobStatusRunning, job.Properties.Status)
+	}
 }
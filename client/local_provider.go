@@ -0,0 +1,353 @@
+/* Copyright 2017, RadiantBlue Technologies, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+func init() {
+	RegisterProvider("local", &ProviderType{
+		New: func(cfg *Config) (CatalogProvider, error) {
+			return NewLocalProvider(cfg)
+		},
+		Fields: []string{"local_dir"},
+	})
+}
+
+// LocalProvider finds scenes on disk, with no network: Landsat scene
+// folders (catalog "landsat"), Sentinel-2 .SAFE folders ("sentinel"), and
+// the items of static STAC catalogs (by collection), anywhere under its
+// directory. Bands are file: URLs, and are "downloaded" by hard linking,
+// or copying where a link cannot be made.
+type LocalProvider struct {
+	dir string
+
+	mu    sync.Mutex
+	index *localIndex // nil until the directory has been read
+}
+
+// the scenes found under the directory
+type localIndex struct {
+	catalogs map[string][]*CatalogFeature // catalog -> scenes, newest first
+	scenes   map[string]*CatalogFeature   // "<catalog>:<scene>" -> scene
+}
+
+// NewLocalProvider builds the provider from the config's LocalDir. The
+// directory is read when the provider is first used.
+func NewLocalProvider(cfg *Config) (*LocalProvider, error) {
+	err := cfg.require("local_dir")
+	if err != nil {
+		return nil, err
+	}
+
+	dir, err := filepath.Abs(cfg.LocalDir)
+	if err != nil {
+		return nil, err
+	}
+	stat, err := os.Stat(dir)
+	if err != nil {
+		return nil, fmt.Errorf("config has a bad local_dir: %s", err.Error())
+	}
+	if !stat.IsDir() {
+		return nil, fmt.Errorf("config has a bad local_dir: '%s' is not a directory", cfg.LocalDir)
+	}
+
+	return &LocalProvider{dir: dir}, nil
+}
+
+// reads the directory, until it has been read once: a failure, perhaps of
+// a disk not yet mounted, is tried again the next time
+func (p *LocalProvider) load() (*localIndex, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.index == nil {
+		index, err := indexDir(p.dir)
+		if err != nil {
+			return nil, err
+		}
+		p.index = index
+	}
+	return p.index, nil
+}
+
+// walks the directory for scenes. A scene or folder below it that cannot be
+// read is left out, and logged, rather than failing the rest.
+func indexDir(dir string) (*localIndex, error) {
+	index := &localIndex{
+		catalogs: map[string][]*CatalogFeature{},
+		scenes:   map[string]*CatalogFeature{},
+	}
+	add := func(catalog string, f *CatalogFeature) {
+		key := catalog + ":" + f.Id
+		if _, ok := index.scenes[key]; ok {
+			verbosef("%s: duplicate scene, ignored", key)
+			return
+		}
+		index.scenes[key] = f
+		index.catalogs[catalog] = append(index.catalogs[catalog], f)
+	}
+
+	landsat := map[string]*landsatScene{} // dir and scene id -> files
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if path == dir {
+				return err
+			}
+			verbosef("%s: %s", path, err.Error())
+			if info != nil && info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		name := info.Name()
+		switch {
+		case info.IsDir() && strings.HasSuffix(name, ".SAFE"):
+			f, err := readSentinelScene(path)
+			if err != nil {
+				verbosef("%s: %s", path, err.Error())
+			} else {
+				add(sentinelCatalog, f)
+			}
+			return filepath.SkipDir
+		case info.IsDir():
+			return nil
+		case strings.EqualFold(filepath.Ext(name), ".json"):
+			catalog, f, err := readSTACItemFile(path)
+			if err != nil {
+				verbosef("%s: %s", path, err.Error())
+			} else if f != nil {
+				add(catalog, f)
+			}
+		default:
+			addLandsatFile(landsat, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	keys := []string{}
+	for key := range landsat {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		f, err := landsat[key].feature()
+		if err != nil {
+			verbosef("%s: %s", key, err.Error())
+			continue
+		}
+		add(landsatCatalog, f)
+	}
+
+	for _, scenes := range index.catalogs {
+		sort.SliceStable(scenes, func(i, j int) bool {
+			return acquired(scenes[i]).After(acquired(scenes[j]))
+		})
+	}
+	return index, nil
+}
+
+// when the scene was taken, or the zero time if that is not known
+func acquired(f *CatalogFeature) time.Time {
+	t, _ := time.Parse(time.RFC3339Nano, f.Properties.AcquiredDate)
+	return t
+}
+
+// the file: URL of a path, as band URLs are given
+func fileURL(path string) string {
+	path = filepath.ToSlash(path)
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path // a Windows drive letter
+	}
+	return (&url.URL{Scheme: "file", Path: path}).String()
+}
+
+// the path of a file: URL
+func filePath(rawurl string) (string, error) {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return "", err
+	}
+	if u.Scheme != "file" || (u.Host != "" && u.Host != "localhost") {
+		return "", fmt.Errorf("not a local file: %s", rawurl)
+	}
+	path := u.Path
+	if len(path) > 2 && path[0] == '/' && path[2] == ':' {
+		path = path[1:] // a Windows drive letter
+	}
+	return filepath.FromSlash(path), nil
+}
+
+//---------------------------------------------------------------------
+
+func (p *LocalProvider) SceneInfo(ctx context.Context, id string) (*CatalogFeature, error) {
+	_, _, err := splitId(id)
+	if err != nil {
+		return nil, err
+	}
+
+	index, err := p.load()
+	if err != nil {
+		return nil, err
+	}
+
+	f, ok := index.scenes[id]
+	if !ok {
+		return nil, fmt.Errorf("scene %s: %w", id, ErrNotFound)
+	}
+	return copyCatalogFeature(f), nil
+}
+
+// Search returns the matching scenes of the catalog, newest first.
+func (p *LocalProvider) Search(ctx context.Context, catalog string, query *SearchQuery) (*Catalog, error) {
+	index, err := p.load()
+	if err != nil {
+		return nil, err
+	}
+
+	scenes, ok := index.catalogs[catalog]
+	if !ok {
+		return nil, fmt.Errorf("catalog %s: %w", catalog, ErrNotFound)
+	}
+
+	result := &Catalog{Type: "FeatureCollection", Features: []*CatalogFeature{}}
+	for _, f := range scenes {
+		if query.Limit > 0 && len(result.Features) >= query.Limit {
+			break
+		}
+		if query.matches(f) {
+			result.Features = append(result.Features, copyCatalogFeature(f))
+		}
+	}
+	return result, nil
+}
+
+// ListAssets returns the scene's band files.
+func (p *LocalProvider) ListAssets(ctx context.Context, id string) ([]*Asset, error) {
+	info, err := p.SceneInfo(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return bandAssets(info), nil
+}
+
+// DownloadAsset hard links the band's file to path, or copies it if the
+// two are on different file systems. Either way the file appears at path
+// only once complete. A link shares the original's contents: writing to
+// one changes the other.
+func (p *LocalProvider) DownloadAsset(ctx context.Context, asset *Asset, path string, progress ProgressReporter) (int64, error) {
+	src, err := filePath(asset.URL)
+	if err != nil {
+		return 0, err
+	}
+	stat, err := os.Stat(src)
+	if err != nil {
+		return 0, err
+	}
+	if err = ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	partial := path + ".part"
+	os.Remove(partial)
+
+	if os.Link(src, partial) == nil {
+		err = os.Rename(partial, path)
+		// renaming over another link to the same file does nothing
+		os.Remove(partial)
+		if err != nil {
+			return 0, err
+		}
+		if progress != nil {
+			progress.Report(&Progress{File: filepath.Base(path), Done: stat.Size(), Total: stat.Size(), Finished: true})
+		}
+		return stat.Size(), nil
+	}
+
+	n, err := copyFile(ctx, src, partial, stat.Size(), filepath.Base(path), progress)
+	if err == nil {
+		err = os.Rename(partial, path)
+	}
+	if err != nil {
+		os.Remove(partial)
+		if progress != nil {
+			progress.Report(&Progress{File: filepath.Base(path), Total: stat.Size(), Finished: true, Err: err})
+		}
+		return 0, err
+	}
+	return n, nil
+}
+
+// copies the file, stopping if the context is cancelled
+func copyFile(ctx context.Context, src string, dst string, size int64, name string, progress ProgressReporter) (int64, error) {
+	in, err := os.Open(src)
+	if err != nil {
+		return 0, err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return 0, err
+	}
+
+	var w io.Writer = out
+	var meter *progressWriter
+	if progress != nil {
+		meter = newProgressWriter(progress, name, 0, size)
+		w = io.MultiWriter(out, meter)
+	}
+
+	n, err := io.Copy(w, &contextReader{ctx: ctx, r: in})
+	cerr := out.Close()
+	if err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	if meter != nil {
+		meter.finish(nil)
+	}
+	return n, nil
+}
+
+// a reader that fails once its context is done
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (r *contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.r.Read(p)
+}
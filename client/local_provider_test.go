@@ -0,0 +1,322 @@
+/* Copyright 2017, RadiantBlue Technologies, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testMTL = `GROUP = L1_METADATA_FILE
+  GROUP = PRODUCT_METADATA
+    SPACECRAFT_ID = "LANDSAT_8"
+    DATE_ACQUIRED = 2017-07-31
+    SCENE_CENTER_TIME = "02:58:35.1234560Z"
+    CORNER_UL_LAT_PRODUCT = 40.60000
+    CORNER_UL_LON_PRODUCT = 115.60000
+    CORNER_UR_LAT_PRODUCT = 40.60000
+    CORNER_UR_LON_PRODUCT = 118.30000
+    CORNER_LL_LAT_PRODUCT = 38.50000
+    CORNER_LL_LON_PRODUCT = 115.60000
+    CORNER_LR_LAT_PRODUCT = 38.50000
+    CORNER_LR_LON_PRODUCT = 118.30000
+  END_GROUP = PRODUCT_METADATA
+  GROUP = IMAGE_ATTRIBUTES
+    CLOUD_COVER = 3.40
+  END_GROUP = IMAGE_ATTRIBUTES
+  GROUP = PROJECTION_PARAMETERS
+    GRID_CELL_SIZE_REFLECTIVE = 30.00
+  END_GROUP = PROJECTION_PARAMETERS
+END_GROUP = L1_METADATA_FILE
+END
+`
+
+const testSentinelMTD = `<?xml version="1.0" encoding="UTF-8"?>
+<n1:Level-1C_User_Product xmlns:n1="https://psd-14.sentinel2.eo.esa.int/PSD/User_Product_Level-1C.xsd">
+  <n1:General_Info>
+    <Product_Info>
+      <PRODUCT_START_TIME>2017-08-01T02:55:51.026Z</PRODUCT_START_TIME>
+      <Datatake datatakeIdentifier="GS2A_20170801T025551_011033_N02.05">
+        <SPACECRAFT_NAME>Sentinel-2A</SPACECRAFT_NAME>
+      </Datatake>
+    </Product_Info>
+  </n1:General_Info>
+  <n1:Geometric_Info>
+    <Product_Footprint>
+      <Product_Footprint>
+        <Global_Footprint>
+          <EXT_POS_LIST>40.5 116.0 40.5 117.3 39.5 117.3 39.5 116.0 40.5 116.0 </EXT_POS_LIST>
+        </Global_Footprint>
+      </Product_Footprint>
+    </Product_Footprint>
+  </n1:Geometric_Info>
+  <n1:Quality_Indicators_Info>
+    <Cloud_Coverage_Assessment>12.5</Cloud_Coverage_Assessment>
+  </n1:Quality_Indicators_Info>
+</n1:Level-1C_User_Product>
+`
+
+const testSTACItem = `{
+  "type": "Feature",
+  "stac_version": "1.0.0",
+  "id": "m_3811720_ne_11_060_20190702",
+  "collection": "naip",
+  "bbox": [-117.9, 38.9, -117.8, 39.0],
+  "geometry": null,
+  "properties": {"datetime": "2019-07-02T00:00:00Z", "gsd": 0.6, "platform": "naip"},
+  "links": [{"rel": "self", "href": "https://example.com/naip/m_3811720_ne_11_060_20190702.json"}],
+  "assets": {
+    "image": {"href": "./m_3811720_ne_11_060_20190702.tif", "type": "image/tiff; application=geotiff", "roles": ["data"]},
+    "thumbnail": {"href": "./thumb.jpg", "type": "image/jpeg", "roles": ["thumbnail"]}
+  }
+}`
+
+// writes the files, by path under dir
+func writeTree(t *testing.T, dir string, files map[string]string) {
+	for name, contents := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		err := os.MkdirAll(filepath.Dir(path), 0755)
+		if err == nil {
+			err = ioutil.WriteFile(path, []byte(contents), 0644)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func newTestLocalDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "bf-client")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	safe := "sentinel/S2A_MSIL1C_20170801T025551_N0205_R032_T50SLJ_20170801T030000.SAFE/"
+	l2a := "sentinel/S2B_MSIL2A_20170805T025549_N0205_R032_T50SLJ_20170805T030000.SAFE/GRANULE/L2A_T50SLJ/IMG_DATA/"
+	writeTree(t, dir, map[string]string{
+		"landsat/LC81260322017212LGN00/LC81260322017212LGN00_B1.TIF":  "B1",
+		"landsat/LC81260322017212LGN00/LC81260322017212LGN00_B2.TIF":  "B2",
+		"landsat/LC81260322017212LGN00/LC81260322017212LGN00_MTL.txt": testMTL,
+		"landsat/LC08_L1TP_126033_20170815_20170825_01_T1_B4.TIF":     "B4",
+		"landsat/LC08_L1TP_126033_20170815_20170825_01_T1_BQA.TIF":    "QA",
+
+		safe + "MTD_MSIL1C.xml": testSentinelMTD,
+		safe + "GRANULE/L1C_T50SLJ/IMG_DATA/T50SLJ_20170801T025551_B02.jp2": "B02",
+		safe + "GRANULE/L1C_T50SLJ/IMG_DATA/T50SLJ_20170801T025551_TCI.jp2": "TCI",
+		l2a + "R10m/T50SLJ_20170805T025549_B02_10m.jp2":                     "B02",
+		l2a + "R20m/T50SLJ_20170805T025549_B02_20m.jp2":                     "B02",
+
+		"stac/catalog.json":                           `{"type": "Catalog", "stac_version": "1.0.0", "id": "local", "links": []}`,
+		"stac/naip/m_3811720_ne_11_060_20190702.json": testSTACItem,
+		"stac/naip/m_3811720_ne_11_060_20190702.tif":  "NAIP",
+		"notes.json": `["not", "stac"]`,
+	})
+	return dir
+}
+
+func TestLocalProvider(t *testing.T) {
+	assert := assert.New(t)
+
+	dir := newTestLocalDir(t)
+
+	// chosen by the settings, needing only the directory
+	withBeachfrontrc(t, `{}`)
+	SetOverride("local_dir", dir)
+	SetOverride("provider", "local")
+	c, err := NewCatalogClient()
+	assert.NoError(err)
+	assert.IsType(&LocalProvider{}, c.Provider())
+
+	feature, err := c.GetInfoForScene("landsat:LC81260322017212LGN00")
+	assert.NoError(err)
+	assert.Equal("2017-07-31T02:58:35.123456Z", feature.Properties.AcquiredDate)
+	assert.Equal(3.4, feature.Properties.CloudCover)
+	assert.Equal(30.0, feature.Properties.Resolution)
+	assert.Equal("Landsat8", feature.Properties.SensorName)
+	assert.Equal("geotiff", feature.Properties.FileFormat)
+	assert.Equal([4]float64{115.6, 38.5, 118.3, 40.6}, feature.Bbox)
+	assert.Equal("Polygon", feature.Geometry.Type())
+	assert.Len(feature.Properties.Bands, 2)
+	assert.True(strings.HasPrefix(feature.Properties.Bands["coastal"], "file:///"))
+
+	// no MTL: the date comes from the id
+	feature, err = c.GetInfoForScene("landsat:LC08_L1TP_126033_20170815_20170825_01_T1")
+	assert.NoError(err)
+	assert.Equal("2017-08-15T00:00:00Z", feature.Properties.AcquiredDate)
+	assert.Equal([]string{"red"}, keys(feature.Properties.Bands))
+
+	feature, err = c.GetInfoForScene("sentinel:S2A_MSIL1C_20170801T025551_N0205_R032_T50SLJ_20170801T030000")
+	assert.NoError(err)
+	assert.Equal("2017-08-01T02:55:51.026Z", feature.Properties.AcquiredDate)
+	assert.Equal(12.5, feature.Properties.CloudCover)
+	assert.Equal("Sentinel-2A", feature.Properties.SensorName)
+	assert.Equal([4]float64{116, 39.5, 117.3, 40.5}, feature.Bbox)
+	assert.Equal([]string{"blue"}, keys(feature.Properties.Bands))
+
+	// the finest of a Level-2A product's resolutions
+	feature, err = c.GetInfoForScene("sentinel:S2B_MSIL2A_20170805T025549_N0205_R032_T50SLJ_20170805T030000")
+	assert.NoError(err)
+	assert.True(strings.HasSuffix(feature.Properties.Bands["blue"], "_B02_10m.jp2"))
+
+	// a static STAC item, with its asset found beside it, not at its self link
+	feature, err = c.GetInfoForScene("naip:m_3811720_ne_11_060_20190702")
+	assert.NoError(err)
+	assert.Equal(0.6, feature.Properties.Resolution)
+	assert.Equal(fileURL(filepath.Join(dir, "stac", "naip", "m_3811720_ne_11_060_20190702.tif")), feature.Properties.Bands["image"])
+
+	_, err = c.GetInfoForScene("landsat:LC80000000000000LGN00")
+	assert.True(errors.Is(err, ErrNotFound))
+
+	// newest first
+	catalog, err := c.SearchCatalog("landsat", nil)
+	assert.NoError(err)
+	assert.Len(catalog.Features, 2)
+	assert.Equal("LC08_L1TP_126033_20170815_20170825_01_T1", catalog.Features[0].Id)
+
+	// the scene without an MTL has no footprint, so is not ruled out
+	catalog, err = c.SearchCatalog("landsat", &SearchQuery{Bbox: []float64{117, 39, 120, 41}})
+	assert.NoError(err)
+	assert.Len(catalog.Features, 2)
+	catalog, err = c.SearchCatalog("landsat", &SearchQuery{Bbox: []float64{-1, -1, 1, 1}})
+	assert.NoError(err)
+	assert.Len(catalog.Features, 1)
+	assert.Equal("LC08_L1TP_126033_20170815_20170825_01_T1", catalog.Features[0].Id)
+
+	catalog, err = c.SearchCatalog("sentinel", &SearchQuery{MaxCloudCover: CloudCover(10)})
+	assert.NoError(err)
+	assert.Len(catalog.Features, 1)
+
+	catalog, err = c.SearchCatalog("sentinel", &SearchQuery{AcquiredAfter: time.Date(2017, 8, 2, 0, 0, 0, 0, time.UTC)})
+	assert.NoError(err)
+	assert.Len(catalog.Features, 1)
+
+	_, err = c.SearchCatalog("modis", nil)
+	assert.True(errors.Is(err, ErrNotFound))
+}
+
+func TestLocalProviderUnreadable(t *testing.T) {
+	assert := assert.New(t)
+
+	dir := newTestLocalDir(t)
+	p, err := NewLocalProvider(&Config{LocalDir: dir})
+	assert.NoError(err)
+
+	// a folder that can't be read is left out
+	if os.Geteuid() != 0 {
+		locked := filepath.Join(dir, "sentinel")
+		assert.NoError(os.Chmod(locked, 0))
+		defer os.Chmod(locked, 0755)
+
+		buf := withLogLevel(t, LogVerbose)
+		catalog, err := p.Search(context.Background(), "landsat", &SearchQuery{})
+		assert.NoError(err)
+		assert.Len(catalog.Features, 2)
+		assert.Contains(buf.String(), "permission denied")
+		_, err = p.Search(context.Background(), "sentinel", &SearchQuery{})
+		assert.True(errors.Is(err, ErrNotFound))
+
+		os.Chmod(locked, 0755)
+		p, err = NewLocalProvider(&Config{LocalDir: dir})
+		assert.NoError(err)
+	}
+
+	// the directory going missing is not remembered once it is back
+	moved := dir + ".moved"
+	assert.NoError(os.Rename(dir, moved))
+	_, err = p.Search(context.Background(), "landsat", &SearchQuery{})
+	assert.Error(err)
+	assert.NoError(os.Rename(moved, dir))
+	catalog, err := p.Search(context.Background(), "landsat", &SearchQuery{})
+	assert.NoError(err)
+	assert.Len(catalog.Features, 2)
+}
+
+func keys(m map[string]string) []string {
+	result := []string{}
+	for k := range m {
+		result = append(result, k)
+	}
+	return result
+}
+
+func TestLocalProviderDownload(t *testing.T) {
+	assert := assert.New(t)
+
+	dir := newTestLocalDir(t)
+	provider, err := NewLocalProvider(&Config{LocalDir: dir})
+	assert.NoError(err)
+	c := NewCatalogClientWithProvider(provider)
+
+	out, err := ioutil.TempDir("", "bf-client")
+	assert.NoError(err)
+	defer os.RemoveAll(out)
+
+	results, err := c.DoCatalogSceneDownload("landsat:LC81260322017212LGN00", &DownloadOptions{
+		OutputDir: out,
+		Template:  "{scene}/{band}.TIF",
+	})
+	assert.NoError(err)
+	assert.Len(results, 2)
+
+	// hard linked
+	src, err := os.Stat(filepath.Join(dir, "landsat", "LC81260322017212LGN00", "LC81260322017212LGN00_B1.TIF"))
+	assert.NoError(err)
+	dst, err := os.Stat(filepath.Join(out, "LC81260322017212LGN00", "coastal.TIF"))
+	assert.NoError(err)
+	assert.True(os.SameFile(src, dst))
+
+	results, err = c.DoCatalogSceneDownload("landsat:LC81260322017212LGN00", &DownloadOptions{
+		OutputDir: out,
+		Template:  "{scene}/{band}.TIF",
+		Existing:  ExistingOverwrite,
+	})
+	assert.NoError(err)
+	assert.Len(results, 2)
+	assert.False(results[0].Skipped)
+	_, err = os.Stat(filepath.Join(out, "LC81260322017212LGN00", "coastal.TIF.part"))
+	assert.True(os.IsNotExist(err))
+
+	// where a link cannot be made, a copy
+	reports := []*Progress{}
+	copied := filepath.Join(out, "copy.TIF")
+	n, err := copyFile(context.Background(), filepath.Join(dir, "stac", "naip", "m_3811720_ne_11_060_20190702.tif"), copied, 4, "copy.TIF",
+		ProgressFunc(func(p *Progress) { reports = append(reports, p) }))
+	assert.NoError(err)
+	assert.Equal(int64(4), n)
+	byts, err := ioutil.ReadFile(copied)
+	assert.NoError(err)
+	assert.Equal("NAIP", string(byts))
+	assert.True(reports[len(reports)-1].Finished)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = copyFile(ctx, copied, filepath.Join(out, "again.TIF"), 4, "again.TIF", nil)
+	assert.Equal(context.Canceled, err)
+
+	// assets the item keeps elsewhere cannot be had offline
+	_, err = provider.DownloadAsset(context.Background(), &Asset{Name: "x", URL: "https://example.com/x.TIF"}, filepath.Join(out, "x.TIF"), nil)
+	assert.Error(err)
+	assert.Contains(err.Error(), "not a local file")
+}
@@ -0,0 +1,401 @@
+/* Copyright 2017, RadiantBlue Technologies, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"bufio"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/venicegeo/bf-client/geojson"
+)
+
+// The scenes the local provider understands, and the catalogs they are
+// put in. STAC items go in their collection, or stacCatalog if they have
+// none.
+const (
+	landsatCatalog  = "landsat"
+	sentinelCatalog = "sentinel"
+	stacCatalog     = "stac"
+)
+
+// makes a footprint and its bbox from the ring's corners
+func footprint(ring []geojson.Position) (*geojson.Geometry, [4]float64) {
+	bbox := [4]float64{math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)}
+	for _, p := range ring {
+		bbox[0] = math.Min(bbox[0], p[0])
+		bbox[1] = math.Min(bbox[1], p[1])
+		bbox[2] = math.Max(bbox[2], p[0])
+		bbox[3] = math.Max(bbox[3], p[1])
+	}
+	first, last := ring[0], ring[len(ring)-1]
+	if first[0] != last[0] || first[1] != last[1] {
+		ring = append(ring, first)
+	}
+	return &geojson.Geometry{Shape: geojson.Polygon{Coordinates: [][]geojson.Position{ring}}}, bbox
+}
+
+//---------------------------------------------------------------------
+
+// the files of one Landsat scene: "<id>_B<n>.TIF" for each band, as
+// before Collection 1 ("LC81260322017212LGN00_B1.TIF") and since
+// ("LC08_L1TP_126032_20170731_20170811_01_T1_B1.TIF", or with "_SR" or
+// "_ST" before the band), and perhaps "<id>_MTL.txt"
+type landsatScene struct {
+	id    string
+	bands map[int]string // band number -> path
+	mtl   string
+}
+
+var (
+	landsatBandPattern = regexp.MustCompile(`^(L[A-Z]\d{1,2}\w*?)_(?:SR_|ST_)?B(\d{1,2})\.(?i:tif)$`)
+	landsatMTLPattern  = regexp.MustCompile(`^(L[A-Z]\d{1,2}\w*?)_(?i:mtl\.txt)$`)
+)
+
+// band names, by number, for Landsat 8 and 9 (OLI and TIRS) and for the
+// older missions (TM and ETM+)
+var (
+	oliBands = map[int]string{1: "coastal", 2: "blue", 3: "green", 4: "red", 5: "nir", 6: "swir1", 7: "swir2", 8: "panchromatic", 9: "cirrus", 10: "tirs1", 11: "tirs2"}
+	tmBands  = map[int]string{1: "blue", 2: "green", 3: "red", 4: "nir", 5: "swir1", 6: "tir", 7: "swir2", 8: "panchromatic"}
+)
+
+// adds the file to its scene, if it is a Landsat band or MTL file
+func addLandsatFile(scenes map[string]*landsatScene, path string) {
+	name := filepath.Base(path)
+	id, band, mtl := "", 0, false
+	if m := landsatBandPattern.FindStringSubmatch(name); m != nil {
+		id = m[1]
+		band, _ = strconv.Atoi(m[2])
+	} else if m := landsatMTLPattern.FindStringSubmatch(name); m != nil {
+		id, mtl = m[1], true
+	} else {
+		return
+	}
+
+	key := filepath.Join(filepath.Dir(path), id)
+	scene, ok := scenes[key]
+	if !ok {
+		scene = &landsatScene{id: id, bands: map[int]string{}}
+		scenes[key] = scene
+	}
+	if mtl {
+		scene.mtl = path
+	} else {
+		scene.bands[band] = path
+	}
+}
+
+// the mission number: 8 for "LC08_..." and "LC8..."
+func (s *landsatScene) mission() int {
+	digits := s.id[2:3]
+	if len(s.id) > 4 && s.id[4] == '_' {
+		digits = s.id[2:4]
+	}
+	n, _ := strconv.Atoi(digits)
+	return n
+}
+
+// the acquisition date in the scene id, at midnight
+func (s *landsatScene) date() (time.Time, error) {
+	if fields := strings.Split(s.id, "_"); len(fields) >= 4 {
+		return time.Parse("20060102", fields[3])
+	}
+	if len(s.id) == 21 {
+		year, err := strconv.Atoi(s.id[9:13])
+		if err != nil {
+			return time.Time{}, err
+		}
+		day, err := strconv.Atoi(s.id[13:16])
+		if err != nil {
+			return time.Time{}, err
+		}
+		return time.Date(year, 1, day, 0, 0, 0, 0, time.UTC), nil
+	}
+	return time.Time{}, fmt.Errorf("no date in scene id %s", s.id)
+}
+
+func (s *landsatScene) feature() (*CatalogFeature, error) {
+	if len(s.bands) == 0 {
+		return nil, fmt.Errorf("no band files for %s", s.id)
+	}
+
+	names := tmBands
+	if s.mission() >= 8 {
+		names = oliBands
+	}
+
+	f := &CatalogFeature{
+		Type: "Feature",
+		Id:   s.id,
+		Properties: &PropertiesInfo{
+			Bands:      map[string]string{},
+			FileFormat: "geotiff",
+			Resolution: 30,
+			SensorName: fmt.Sprintf("Landsat%d", s.mission()),
+		},
+	}
+	for band, path := range s.bands {
+		name, ok := names[band]
+		if !ok {
+			name = fmt.Sprintf("b%d", band)
+		}
+		f.Properties.Bands[name] = fileURL(path)
+	}
+
+	date, err := s.date()
+	if err != nil {
+		return nil, err
+	}
+	f.Properties.AcquiredDate = date.Format(time.RFC3339Nano)
+
+	if s.mtl == "" {
+		verbosef("%s: no MTL file, so the footprint and cloud cover are not known", s.id)
+		return f, nil
+	}
+	mtl, err := readMTL(s.mtl)
+	if err != nil {
+		return nil, err
+	}
+
+	if day := mtl["DATE_ACQUIRED"]; day != "" {
+		t, err := time.Parse(time.RFC3339Nano, day+"T"+mtl["SCENE_CENTER_TIME"])
+		if err != nil {
+			t, err = time.Parse("2006-01-02", day)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: bad DATE_ACQUIRED: %s", s.mtl, day)
+		}
+		f.Properties.AcquiredDate = t.UTC().Format(time.RFC3339Nano)
+	}
+	if v, err := strconv.ParseFloat(mtl["CLOUD_COVER"], 64); err == nil {
+		f.Properties.CloudCover = v
+	}
+	if v, err := strconv.ParseFloat(mtl["GRID_CELL_SIZE_REFLECTIVE"], 64); err == nil {
+		f.Properties.Resolution = v
+	}
+	if v := mtl["SPACECRAFT_ID"]; strings.HasPrefix(v, "LANDSAT_") {
+		f.Properties.SensorName = "Landsat" + strings.TrimPrefix(v, "LANDSAT_")
+	}
+
+	ring := []geojson.Position{}
+	for _, corner := range []string{"UL", "UR", "LR", "LL"} {
+		lat, err := strconv.ParseFloat(mtl["CORNER_"+corner+"_LAT_PRODUCT"], 64)
+		if err != nil {
+			break
+		}
+		lon, err := strconv.ParseFloat(mtl["CORNER_"+corner+"_LON_PRODUCT"], 64)
+		if err != nil {
+			break
+		}
+		ring = append(ring, geojson.Position{lon, lat})
+	}
+	if len(ring) == 4 {
+		f.Geometry, f.Bbox = footprint(ring)
+	}
+
+	return f, nil
+}
+
+// reads the "KEY = VALUE" lines of a Landsat MTL file, keeping the first
+// of any key
+func readMTL(path string) (map[string]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	values := map[string]string{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		parts := strings.SplitN(scanner.Text(), "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		key := strings.TrimSpace(parts[0])
+		if _, ok := values[key]; !ok {
+			values[key] = strings.Trim(strings.TrimSpace(parts[1]), `"`)
+		}
+	}
+	return values, scanner.Err()
+}
+
+//---------------------------------------------------------------------
+
+// the parts of a Sentinel-2 product's MTD_MSIL1C.xml or MTD_MSIL2A.xml
+// that make a CatalogFeature
+type sentinelMetadata struct {
+	StartTime  string  `xml:"General_Info>Product_Info>PRODUCT_START_TIME"`
+	Spacecraft string  `xml:"General_Info>Product_Info>Datatake>SPACECRAFT_NAME"`
+	Footprint  string  `xml:"Geometric_Info>Product_Footprint>Product_Footprint>Global_Footprint>EXT_POS_LIST"` // "lat lon lat lon ..."
+	CloudCover float64 `xml:"Quality_Indicators_Info>Cloud_Coverage_Assessment"`
+}
+
+// the band files of a product, "..._B01.jp2", or "..._B02_10m.jp2" in
+// Level-2A products, which hold some bands at several resolutions
+var sentinelBandPattern = regexp.MustCompile(`_(B\d[\dA])(?:_(\d+)m)?\.jp2$`)
+
+var sentinelBands = map[string]string{
+	"B01": "coastal", "B02": "blue", "B03": "green", "B04": "red",
+	"B05": "rededge1", "B06": "rededge2", "B07": "rededge3", "B08": "nir",
+	"B8A": "nir08", "B09": "watervapor", "B10": "cirrus", "B11": "swir1", "B12": "swir2",
+}
+
+// reads the product in a .SAFE folder, with its bands at their finest
+// resolution
+func readSentinelScene(dir string) (*CatalogFeature, error) {
+	id := strings.TrimSuffix(filepath.Base(dir), ".SAFE")
+
+	f := &CatalogFeature{
+		Type: "Feature",
+		Id:   id,
+		Properties: &PropertiesInfo{
+			Bands:      map[string]string{},
+			FileFormat: "jpeg2000",
+			Resolution: 10,
+			SensorName: "Sentinel-2",
+		},
+	}
+
+	resolutions := map[string]int{}
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		m := sentinelBandPattern.FindStringSubmatch(info.Name())
+		if info.IsDir() || m == nil {
+			return nil
+		}
+		name, ok := sentinelBands[m[1]]
+		if !ok {
+			return nil
+		}
+		res, _ := strconv.Atoi(m[2])
+		if best, ok := resolutions[name]; ok && best <= res {
+			return nil
+		}
+		resolutions[name] = res
+		f.Properties.Bands[name] = fileURL(path)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(f.Properties.Bands) == 0 {
+		return nil, fmt.Errorf("no band files for %s", id)
+	}
+
+	paths, _ := filepath.Glob(filepath.Join(dir, "MTD_MSIL*.xml"))
+	if len(paths) == 0 {
+		// no metadata: the date is in the id, "S2A_MSIL1C_20170731T025551_..."
+		fields := strings.Split(id, "_")
+		if len(fields) < 3 {
+			return nil, fmt.Errorf("no metadata, and no date in product id %s", id)
+		}
+		t, err := time.Parse("20060102T150405", fields[2])
+		if err != nil {
+			return nil, fmt.Errorf("no metadata, and no date in product id %s", id)
+		}
+		f.Properties.AcquiredDate = t.Format(time.RFC3339Nano)
+		return f, nil
+	}
+
+	byts, err := ioutil.ReadFile(paths[0])
+	if err != nil {
+		return nil, err
+	}
+	md := &sentinelMetadata{}
+	err = xml.Unmarshal(byts, md)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", paths[0], err.Error())
+	}
+
+	t, err := time.Parse(time.RFC3339Nano, md.StartTime)
+	if err != nil {
+		return nil, fmt.Errorf("%s: bad PRODUCT_START_TIME: %s", paths[0], md.StartTime)
+	}
+	f.Properties.AcquiredDate = t.UTC().Format(time.RFC3339Nano)
+	f.Properties.CloudCover = md.CloudCover
+	if md.Spacecraft != "" {
+		f.Properties.SensorName = md.Spacecraft
+	}
+
+	coords := strings.Fields(md.Footprint)
+	ring := []geojson.Position{}
+	for i := 0; i+1 < len(coords); i += 2 {
+		lat, err := strconv.ParseFloat(coords[i], 64)
+		if err != nil {
+			break
+		}
+		lon, err := strconv.ParseFloat(coords[i+1], 64)
+		if err != nil {
+			break
+		}
+		ring = append(ring, geojson.Position{lon, lat})
+	}
+	if len(ring) >= 3 {
+		f.Geometry, f.Bbox = footprint(ring)
+	}
+
+	return f, nil
+}
+
+//---------------------------------------------------------------------
+
+// reads a STAC item from a static catalog, with its assets resolved
+// against the file rather than any self link, which may be where the
+// catalog was first published. Returns a nil feature for JSON files that
+// are not items, such as the catalog.json and collection.json files.
+func readSTACItemFile(path string) (string, *CatalogFeature, error) {
+	byts, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", nil, err
+	}
+
+	item := &stacItem{}
+	err = json.Unmarshal(byts, item)
+	if err != nil {
+		return "", nil, err
+	}
+	if item.Type != "Feature" || item.STACVersion == "" {
+		return "", nil, nil
+	}
+
+	base, err := url.Parse(fileURL(path))
+	if err != nil {
+		return "", nil, err
+	}
+	f, err := item.feature(base)
+	if err != nil {
+		return "", nil, err
+	}
+
+	catalog := item.Collection
+	if catalog == "" {
+		catalog = stacCatalog
+	}
+	return catalog, f, nil
+}
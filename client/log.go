@@ -0,0 +1,176 @@
+/* Copyright 2017, RadiantBlue Technologies, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LogLevel says how much the clients write to the standard logger.
+type LogLevel int
+
+const (
+	LogQuiet   LogLevel = iota // only warnings: failures are returned as errors
+	LogVerbose                 // each request: method, URL, status, time and size
+	LogDebug                   // also headers, retries and the methods called
+)
+
+var logLevel = LogQuiet
+
+// SetLogLevel sets how much the clients log. Secrets in URLs and headers
+// are always redacted.
+func SetLogLevel(level LogLevel) {
+	logLevel = level
+}
+
+// for what is skipped rather than failed, logged at every level
+func warnf(format string, v ...interface{}) {
+	log.Printf("warning: "+format, v...)
+}
+
+func verbosef(format string, v ...interface{}) {
+	if logLevel >= LogVerbose {
+		log.Printf(format, v...)
+	}
+}
+
+func debugf(format string, v ...interface{}) {
+	if logLevel >= LogDebug {
+		log.Printf(format, v...)
+	}
+}
+
+//---------------------------------------------------------------------
+
+// headers whose values are never logged
+var secretHeaders = map[string]bool{
+	"Authorization":       true,
+	"Proxy-Authorization": true,
+	"Cookie":              true,
+	"Set-Cookie":          true,
+	"X-Api-Key":           true,
+}
+
+// more such headers, as credentials are sent in them
+var credentialHeaders sync.Map
+
+func addSecretHeader(name string) {
+	credentialHeaders.Store(http.CanonicalHeaderKey(name), true)
+}
+
+func isSecretHeader(name string) bool {
+	name = http.CanonicalHeaderKey(name)
+	_, ok := credentialHeaders.Load(name)
+	return ok || secretHeaders[name]
+}
+
+// traceTransport logs each request it makes, as the log level says.
+type traceTransport struct {
+	base http.RoundTripper
+}
+
+func newTraceTransport(base http.RoundTripper) *traceTransport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &traceTransport{base: base}
+}
+
+func (t *traceTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if logLevel < LogVerbose {
+		return t.base.RoundTrip(req)
+	}
+
+	url := redactURL(req.URL)
+	debugf("%s %s\n%s", req.Method, url, formatHeaders(req.Header, "> "))
+
+	start := time.Now()
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		verbosef("%s %s: failed after %s: %s", req.Method, url, since(start), err.Error())
+		return nil, err
+	}
+
+	debugf("%s %s: %s\n%s", req.Method, url, resp.Status, formatHeaders(resp.Header, "< "))
+
+	// the size and time are known once the body has been read
+	resp.Body = &tracedBody{
+		ReadCloser: resp.Body,
+		done: func(n int64) {
+			verbosef("%s %s: %d, %d bytes in %s", req.Method, url, resp.StatusCode, n, since(start))
+		},
+	}
+	return resp, nil
+}
+
+func since(start time.Time) time.Duration {
+	return time.Since(start).Round(time.Millisecond)
+}
+
+// one "prefix Name: value" line per header, sorted, with secrets redacted
+func formatHeaders(header http.Header, prefix string) string {
+	names := []string{}
+	for name := range header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	lines := []string{}
+	for _, name := range names {
+		for _, value := range header[name] {
+			if isSecretHeader(name) {
+				value = redactHeader(value)
+			}
+			lines = append(lines, fmt.Sprintf("%s%s: %s", prefix, name, value))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// keeps the scheme of an Authorization value, "Basic ****"
+func redactHeader(value string) string {
+	if i := strings.Index(value, " "); i > 0 {
+		return value[:i] + " ****"
+	}
+	return "****"
+}
+
+// counts what is read from a response body, reporting once it is closed
+type tracedBody struct {
+	io.ReadCloser
+	n    int64
+	done func(n int64)
+	once sync.Once
+}
+
+func (b *tracedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.n += int64(n)
+	return n, err
+}
+
+func (b *tracedBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(func() { b.done(b.n) })
+	return err
+}
@@ -0,0 +1,78 @@
+/* Copyright 2017, RadiantBlue Technologies, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// captures what the clients log at the given level
+func withLogLevel(t *testing.T, level LogLevel) *bytes.Buffer {
+	buf := &bytes.Buffer{}
+	log.SetOutput(buf)
+	SetLogLevel(level)
+	t.Cleanup(func() {
+		log.SetOutput(os.Stderr)
+		SetLogLevel(LogQuiet)
+	})
+	return buf
+}
+
+func TestTraceTransport(t *testing.T) {
+	assert := assert.New(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Set-Cookie", "session=secret-cookie")
+		w.Write([]byte(`{"features": []}`))
+	}))
+	defer server.Close()
+
+	httpClient := &http.Client{Transport: newTraceTransport(nil)}
+	url := server.URL + "/planet/discover/landsat?PL_API_KEY=secret-key"
+
+	// quiet
+	buf := withLogLevel(t, LogQuiet)
+	_, err := doHttpGetJSONWithAuth(context.Background(), httpClient, url, "secret-auth", 200)
+	assert.NoError(err)
+	assert.Empty(buf.String())
+
+	// verbose: one line, with the size
+	buf = withLogLevel(t, LogVerbose)
+	_, err = doHttpGetJSONWithAuth(context.Background(), httpClient, url, "secret-auth", 200)
+	assert.NoError(err)
+	assert.Contains(buf.String(), "GET "+server.URL+"/planet/discover/landsat?PL_API_KEY=****: 200, 16 bytes in ")
+	assert.NotContains(buf.String(), "Authorization")
+
+	// debug: headers too
+	buf = withLogLevel(t, LogDebug)
+	_, err = doHttpGetJSONWithAuth(context.Background(), httpClient, url, "secret-auth", 200)
+	assert.NoError(err)
+	assert.Contains(buf.String(), "> Authorization: Basic ****")
+	assert.Contains(buf.String(), "< Set-Cookie: ****")
+	assert.Contains(buf.String(), "< Content-Length: 16")
+
+	for _, secret := range []string{"secret-key", "secret-auth", "secret-cookie", "c2VjcmV0LWF1dGg6"} {
+		assert.NotContains(buf.String(), secret)
+	}
+}
@@ -0,0 +1,588 @@
+/* Copyright 2017, RadiantBlue Technologies, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/venicegeo/bf-client/geojson"
+)
+
+// In-memory services, for testing code that uses a Client without a
+// network or a fake server:
+//
+//	jobs := client.NewMemoryJobs()
+//	jobs.AddJob(&client.Job{Id: "j1", Properties: &client.JobProperties{Status: "Running"}})
+//	c := &client.Client{Job: jobs, Catalog: client.NewMemoryCatalog(), ...}
+//
+// They are safe to use from several goroutines, and hand out copies, so
+// callers can't change what they hold. Missing items are reported with
+// errors that match ErrNotFound.
+//
+// Their Context methods fail with the context's error if it is done when
+// they are called. Having nothing to wait on, they don't watch it after
+// that, except that a download checks it again before a file is complete.
+
+// NewMemoryClient returns a Client whose services are all empty in-memory
+// ones.
+func NewMemoryClient() *Client {
+	return &Client{
+		Catalog:   NewMemoryCatalog(),
+		Job:       NewMemoryJobs(),
+		Coastline: NewMemoryCoastlines(),
+		Algorithm: NewMemoryAlgorithms(),
+	}
+}
+
+//---------------------------------------------------------------------
+
+// MemoryCatalog is a CatalogService holding scenes and their band files.
+type MemoryCatalog struct {
+	mu     sync.Mutex
+	scenes map[string][]*CatalogFeature // catalog -> scenes
+	files  map[string][]byte            // band URL -> contents
+}
+
+func NewMemoryCatalog() *MemoryCatalog {
+	return &MemoryCatalog{scenes: map[string][]*CatalogFeature{}, files: map[string][]byte{}}
+}
+
+// AddScene adds the scene to the catalog, or replaces the one with its id.
+// Each of the bands given is added to the scene's bands, as a file named
+// "<scene>_<band>.TIF" holding the contents.
+func (m *MemoryCatalog) AddScene(catalog string, scene *CatalogFeature, bands map[string][]byte) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	f := copyCatalogFeature(scene)
+	if f.Type == "" {
+		f.Type = "Feature"
+	}
+	for name, contents := range bands {
+		u := fmt.Sprintf("memory:///%s/%s/%s_%s.TIF", url.PathEscape(catalog), url.PathEscape(f.Id), f.Id, name)
+		f.Properties.Bands[name] = u
+		m.files[u] = contents
+	}
+
+	scenes := m.scenes[catalog]
+	for i, v := range scenes {
+		if v.Id == f.Id {
+			scenes[i] = f
+			return
+		}
+	}
+	m.scenes[catalog] = append(scenes, f)
+}
+
+func (m *MemoryCatalog) GetInfoForCatalogs() error {
+	return fmt.Errorf("catalog: listing catalogs is not supported")
+}
+
+func (m *MemoryCatalog) GetInfoForScene(id string) (*CatalogFeature, error) {
+	return m.GetInfoForSceneContext(context.Background(), id)
+}
+
+// GetInfoForSceneContext is GetInfoForScene with a context.
+func (m *MemoryCatalog) GetInfoForSceneContext(ctx context.Context, id string) (*CatalogFeature, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	catalog, scene, err := splitId(id)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, f := range m.scenes[catalog] {
+		if f.Id == scene {
+			return copyCatalogFeature(f), nil
+		}
+	}
+	return nil, fmt.Errorf("scene %s: %w", id, ErrNotFound)
+}
+
+func (m *MemoryCatalog) GetInfoForCatalog(id string) (*Catalog, error) {
+	return m.GetInfoForCatalogContext(context.Background(), id)
+}
+
+// GetInfoForCatalogContext is GetInfoForCatalog with a context.
+func (m *MemoryCatalog) GetInfoForCatalogContext(ctx context.Context, id string) (*Catalog, error) {
+	return m.SearchCatalogContext(ctx, id, nil)
+}
+
+func (m *MemoryCatalog) SearchCatalog(id string, query *SearchQuery) (*Catalog, error) {
+	return m.SearchCatalogContext(context.Background(), id, query)
+}
+
+// SearchCatalogContext is SearchCatalog with a context.
+func (m *MemoryCatalog) SearchCatalogContext(ctx context.Context, id string, query *SearchQuery) (*Catalog, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if query == nil {
+		query = &SearchQuery{}
+	}
+	_, err := query.values()
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	scenes, ok := m.scenes[id]
+	if !ok {
+		return nil, fmt.Errorf("catalog %s: %w", id, ErrNotFound)
+	}
+
+	result := &Catalog{Type: "FeatureCollection", Features: []*CatalogFeature{}}
+	for _, f := range scenes {
+		if query.Limit > 0 && len(result.Features) >= query.Limit {
+			break
+		}
+		if query.matches(f) {
+			result.Features = append(result.Features, copyCatalogFeature(f))
+		}
+	}
+	return result, nil
+}
+
+func (m *MemoryCatalog) DoCatalogSceneDownload(id string, opts *DownloadOptions) ([]*DownloadResult, error) {
+	return m.DoCatalogSceneDownloadContext(context.Background(), id, opts)
+}
+
+// DoCatalogSceneDownloadContext is DoCatalogSceneDownload with a context.
+func (m *MemoryCatalog) DoCatalogSceneDownloadContext(ctx context.Context, id string, opts *DownloadOptions) ([]*DownloadResult, error) {
+	info, err := m.GetInfoForSceneContext(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	fetch := func(ctx context.Context, asset *Asset, path string, progress ProgressReporter) (int64, error) {
+		m.mu.Lock()
+		contents, ok := m.files[asset.URL]
+		m.mu.Unlock()
+		if !ok {
+			return 0, fmt.Errorf("band %s of scene %s: %w", asset.Name, id, ErrNotFound)
+		}
+		err := writeMemoryFile(ctx, path, contents)
+		if err != nil {
+			return 0, err
+		}
+		return int64(len(contents)), nil
+	}
+	return downloadBands(ctx, id, bandAssets(info), opts, fetch)
+}
+
+// writes the contents to a ".part" file, renamed to path once written, so
+// the file appears only when complete, as a downloaded one does
+func writeMemoryFile(ctx context.Context, path string, contents []byte) error {
+	partial := path + ".part"
+	err := ioutil.WriteFile(partial, contents, 0644)
+	if err == nil {
+		err = ctx.Err()
+	}
+	if err == nil {
+		err = os.Rename(partial, path)
+	}
+	if err != nil {
+		os.Remove(partial)
+		return err
+	}
+	return nil
+}
+
+func copyCatalogFeature(f *CatalogFeature) *CatalogFeature {
+	c := *f
+	p := PropertiesInfo{}
+	if f.Properties != nil {
+		p = *f.Properties
+	}
+	p.Bands = map[string]string{}
+	if f.Properties != nil {
+		for k, v := range f.Properties.Bands {
+			p.Bands[k] = v
+		}
+	}
+	c.Properties = &p
+	return &c
+}
+
+//---------------------------------------------------------------------
+
+// MemoryJobs is a JobService holding a job list. Jobs change status only
+// when told to, with SetStatus.
+type MemoryJobs struct {
+	mu   sync.Mutex
+	jobs []*Job
+	next int
+}
+
+func NewMemoryJobs() *MemoryJobs {
+	return &MemoryJobs{}
+}
+
+// AddJob adds the job to the list, or replaces the one with its id, and
+// returns the id, which is made up if the job has none.
+func (m *MemoryJobs) AddJob(job *Job) string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.addJob(copyJob(job))
+}
+
+// the caller holds the lock
+func (m *MemoryJobs) addJob(job *Job) string {
+	if job.Id == "" {
+		m.next++
+		job.Id = fmt.Sprintf("memory-job-%d", m.next)
+	}
+	if job.Type == "" {
+		job.Type = "Feature"
+	}
+	for i, v := range m.jobs {
+		if v.Id == job.Id {
+			m.jobs[i] = job
+			return job.Id
+		}
+	}
+	m.jobs = append(m.jobs, job)
+	return job.Id
+}
+
+// SetStatus changes the status of the job, as the service would as the
+// job runs.
+func (m *MemoryJobs) SetStatus(id string, status string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, job := range m.jobs {
+		if job.Id == id {
+			job.Properties.Status = status
+			return nil
+		}
+	}
+	return fmt.Errorf("job %s: %w", id, ErrNotFound)
+}
+
+func (m *MemoryJobs) GetInfoForJobs() (*JobList, error) {
+	return m.GetInfoForJobsContext(context.Background())
+}
+
+// GetInfoForJobsContext is GetInfoForJobs with a context.
+func (m *MemoryJobs) GetInfoForJobsContext(ctx context.Context) (*JobList, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	list := &JobList{Type: "FeatureCollection", Features: []*Job{}}
+	for _, job := range m.jobs {
+		list.Features = append(list.Features, copyJob(job))
+	}
+	return list, nil
+}
+
+func (m *MemoryJobs) GetInfoForJob(id string) (*Job, error) {
+	return m.GetInfoForJobContext(context.Background(), id)
+}
+
+// GetInfoForJobContext is GetInfoForJob with a context.
+func (m *MemoryJobs) GetInfoForJobContext(ctx context.Context, id string) (*Job, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, job := range m.jobs {
+		if job.Id == id {
+			return copyJob(job), nil
+		}
+	}
+	return nil, fmt.Errorf("job %s: %w", id, ErrNotFound)
+}
+
+func (m *MemoryJobs) DoJobSubmit(submission *JobSubmission) (*Job, error) {
+	return m.DoJobSubmitContext(context.Background(), submission)
+}
+
+// DoJobSubmitContext is DoJobSubmit with a context. The job is added as
+// submitted.
+func (m *MemoryJobs) DoJobSubmitContext(ctx context.Context, submission *JobSubmission) (*Job, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	err := submission.validate()
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	job := &Job{Properties: &JobProperties{
+		Name:      submission.Name,
+		Status:    JobStatusSubmitted,
+		CreatedOn: time.Now().UTC().Format(time.RFC3339Nano),
+		SceneId:   submission.SceneId,
+	}}
+	m.addJob(job)
+	return copyJob(job), nil
+}
+
+func (m *MemoryJobs) FindJobs(filter *JobFilter) ([]*Job, error) {
+	return m.FindJobsContext(context.Background(), filter)
+}
+
+// FindJobsContext is FindJobs with a context.
+func (m *MemoryJobs) FindJobsContext(ctx context.Context, filter *JobFilter) ([]*Job, error) {
+	return findJobs(ctx, m, filter)
+}
+
+func (m *MemoryJobs) DoJobDelete(id string) error {
+	return m.DoJobDeleteContext(context.Background(), id)
+}
+
+// DoJobDeleteContext is DoJobDelete with a context.
+func (m *MemoryJobs) DoJobDeleteContext(ctx context.Context, id string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if id == "" {
+		return fmt.Errorf("job deletion requires a job id")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i, job := range m.jobs {
+		if job.Id == id {
+			m.jobs = append(m.jobs[:i], m.jobs[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("unable to delete job %s: %w", id, ErrNotFound)
+}
+
+func (m *MemoryJobs) DoJobDeleteMany(ids []string) ([]string, error) {
+	return m.DoJobDeleteManyContext(context.Background(), ids)
+}
+
+// DoJobDeleteManyContext is DoJobDeleteMany with a context.
+func (m *MemoryJobs) DoJobDeleteManyContext(ctx context.Context, ids []string) ([]string, error) {
+	return deleteJobs(ctx, m, ids)
+}
+
+func (m *MemoryJobs) WaitForJob(id string, opts *WaitOptions) (*Job, error) {
+	return m.WaitForJobContext(context.Background(), id, opts)
+}
+
+// WaitForJobContext is WaitForJob with a context.
+func (m *MemoryJobs) WaitForJobContext(ctx context.Context, id string, opts *WaitOptions) (*Job, error) {
+	return waitForJob(ctx, m, id, opts)
+}
+
+func copyJob(job *Job) *Job {
+	c := *job
+	p := JobProperties{}
+	if job.Properties != nil {
+		p = *job.Properties
+	}
+	c.Properties = &p
+	return &c
+}
+
+//---------------------------------------------------------------------
+
+// MemoryCoastlines is a CoastlineService holding the coastlines of jobs.
+type MemoryCoastlines struct {
+	mu         sync.Mutex
+	coastlines map[string][]byte // job id -> GeoJSON
+}
+
+func NewMemoryCoastlines() *MemoryCoastlines {
+	return &MemoryCoastlines{coastlines: map[string][]byte{}}
+}
+
+// SetCoastline sets the coastline of the job.
+func (m *MemoryCoastlines) SetCoastline(id string, coastline *geojson.FeatureCollection) error {
+	byts, err := json.Marshal(coastline)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.coastlines[id] = byts
+	return nil
+}
+
+func (m *MemoryCoastlines) coastline(id string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	byts, ok := m.coastlines[id]
+	if !ok {
+		return nil, fmt.Errorf("coastline for job %s: %w", id, ErrNotFound)
+	}
+	return byts, nil
+}
+
+func (m *MemoryCoastlines) GetCoastline(id string) (*geojson.FeatureCollection, error) {
+	return m.GetCoastlineContext(context.Background(), id)
+}
+
+// GetCoastlineContext is GetCoastline with a context.
+func (m *MemoryCoastlines) GetCoastlineContext(ctx context.Context, id string) (*geojson.FeatureCollection, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	byts, err := m.coastline(id)
+	if err != nil {
+		return nil, err
+	}
+	coastline := &geojson.FeatureCollection{}
+	err = json.Unmarshal(byts, coastline)
+	if err != nil {
+		return nil, err
+	}
+	return coastline, nil
+}
+
+func (m *MemoryCoastlines) DoDownload(id string, opts *DownloadOptions) (*DownloadResult, error) {
+	return m.DoDownloadContext(context.Background(), id, opts)
+}
+
+// DoDownloadContext is DoDownload with a context.
+func (m *MemoryCoastlines) DoDownloadContext(ctx context.Context, id string, opts *DownloadOptions) (*DownloadResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if opts == nil {
+		opts = &DownloadOptions{}
+	}
+	err := opts.validate()
+	if err != nil {
+		return nil, err
+	}
+
+	byts, err := m.coastline(id)
+	if err != nil {
+		return nil, err
+	}
+
+	filename, err := opts.outputPath(CoastlineTemplate, map[string]string{"job": id})
+	if err != nil {
+		return nil, err
+	}
+
+	skipped, err := checkExisting(filename, opts.Existing)
+	if skipped != nil || err != nil {
+		return skipped, err
+	}
+
+	err = prepareOutputPath(filename)
+	if err != nil {
+		return nil, err
+	}
+	err = writeMemoryFile(ctx, filename, byts)
+	if err != nil {
+		return nil, err
+	}
+
+	return &DownloadResult{File: filename, Bytes: int64(len(byts))}, nil
+}
+
+//---------------------------------------------------------------------
+
+// MemoryAlgorithms is an AlgorithmService holding a list of algorithms.
+type MemoryAlgorithms struct {
+	mu         sync.Mutex
+	algorithms []*AlgorithmInfo
+}
+
+func NewMemoryAlgorithms() *MemoryAlgorithms {
+	return &MemoryAlgorithms{}
+}
+
+// AddAlgorithm adds the algorithm to the list.
+func (m *MemoryAlgorithms) AddAlgorithm(alg *AlgorithmInfo) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	a := *alg
+	m.algorithms = append(m.algorithms, &a)
+}
+
+func (m *MemoryAlgorithms) GetInfoForAll() (*Algorithms, error) {
+	return m.GetInfoForAllContext(context.Background())
+}
+
+// GetInfoForAllContext is GetInfoForAll with a context.
+func (m *MemoryAlgorithms) GetInfoForAllContext(ctx context.Context) (*Algorithms, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	algs := &Algorithms{Algorithms: []*AlgorithmInfo{}}
+	for _, a := range m.algorithms {
+		c := *a
+		algs.Algorithms = append(algs.Algorithms, &c)
+	}
+	return algs, nil
+}
+
+func (m *MemoryAlgorithms) GetInfoForOne(id string) (*AlgorithmInfo, error) {
+	return m.GetInfoForOneContext(context.Background(), id)
+}
+
+// GetInfoForOneContext is GetInfoForOne with a context.
+func (m *MemoryAlgorithms) GetInfoForOneContext(ctx context.Context, id string) (*AlgorithmInfo, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, a := range m.algorithms {
+		if a.ServiceId == id {
+			c := *a
+			return &c, nil
+		}
+	}
+	return nil, fmt.Errorf("algorithm %s: %w", id, ErrNotFound)
+}
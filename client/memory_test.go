@@ -0,0 +1,163 @@
+/* Copyright 2017, RadiantBlue Technologies, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/venicegeo/bf-client/geojson"
+)
+
+func TestMemoryJobs(t *testing.T) {
+	assert := assert.New(t)
+
+	jobs := NewMemoryJobs()
+	c := &Client{Job: jobs}
+
+	job, err := c.Job.DoJobSubmit(&JobSubmission{Name: "j", AlgorithmId: "a", SceneId: "landsat:LC1", PlanetKey: "k"})
+	assert.NoError(err)
+	assert.Equal(JobStatusSubmitted, job.Properties.Status)
+
+	// what is handed out is a copy
+	job.Properties.Status = JobStatusError
+	again, err := c.Job.GetInfoForJob(job.Id)
+	assert.NoError(err)
+	assert.Equal(JobStatusSubmitted, again.Properties.Status)
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		jobs.SetStatus(job.Id, JobStatusSuccess)
+	}()
+	done, err := c.Job.WaitForJob(job.Id, &WaitOptions{Interval: time.Millisecond})
+	assert.NoError(err)
+	assert.Equal(JobStatusSuccess, done.Properties.Status)
+
+	jobs.AddJob(&Job{Id: "old", Properties: &JobProperties{Status: JobStatusError, CreatedOn: "2017-07-01T10:00:00Z"}})
+	found, err := c.Job.FindJobs(&JobFilter{Status: "error"})
+	assert.NoError(err)
+	assert.Len(found, 1)
+
+	deleted, err := c.Job.DoJobDeleteMany([]string{"old", "missing", job.Id})
+	assert.Equal([]string{"old", job.Id}, deleted)
+	assert.True(errors.Is(err, ErrNotFound))
+
+	list, err := c.Job.GetInfoForJobs()
+	assert.NoError(err)
+	assert.Empty(list.Features)
+}
+
+func TestMemoryCatalog(t *testing.T) {
+	assert := assert.New(t)
+
+	catalog := NewMemoryCatalog()
+	catalog.AddScene("landsat", &CatalogFeature{
+		Id:         "LC1",
+		Bbox:       [4]float64{0, 0, 10, 10},
+		Properties: &PropertiesInfo{CloudCover: 5, AcquiredDate: "2017-07-31T02:58:35Z"},
+	}, map[string][]byte{"coastal": []byte("B1"), "blue": []byte("B2")})
+	catalog.AddScene("landsat", &CatalogFeature{Id: "LC2", Properties: &PropertiesInfo{CloudCover: 50}}, nil)
+	c := &Client{Catalog: catalog}
+
+	result, err := c.Catalog.SearchCatalog("landsat", &SearchQuery{MaxCloudCover: CloudCover(10), Bbox: []float64{5, 5, 20, 20}})
+	assert.NoError(err)
+	assert.Len(result.Features, 1)
+	assert.Equal("LC1", result.Features[0].Id)
+
+	_, err = c.Catalog.GetInfoForScene("landsat:LC3")
+	assert.True(errors.Is(err, ErrNotFound))
+
+	dir, err := ioutil.TempDir("", "bf-client")
+	assert.NoError(err)
+	defer os.RemoveAll(dir)
+
+	results, err := c.Catalog.DoCatalogSceneDownload("landsat:LC1", &DownloadOptions{OutputDir: dir, Template: "{scene}/{band}.TIF"})
+	assert.NoError(err)
+	assert.Len(results, 2)
+	byts, err := ioutil.ReadFile(filepath.Join(dir, "LC1", "coastal.TIF"))
+	assert.NoError(err)
+	assert.Equal("B1", string(byts))
+	parts, _ := filepath.Glob(filepath.Join(dir, "LC1", "*.part"))
+	assert.Empty(parts)
+
+	// a cancelled download leaves nothing behind
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = c.Catalog.DoCatalogSceneDownloadContext(ctx, "landsat:LC1", &DownloadOptions{OutputDir: dir, Template: "again/{band}.TIF"})
+	assert.Equal(context.Canceled, err)
+	_, err = os.Stat(filepath.Join(dir, "again"))
+	assert.True(os.IsNotExist(err))
+}
+
+func TestMemoryContext(t *testing.T) {
+	assert := assert.New(t)
+
+	c := NewMemoryClient()
+	c.Job.(*MemoryJobs).AddJob(&Job{Id: "j1", Properties: &JobProperties{Status: JobStatusSuccess}})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := c.Job.GetInfoForJobContext(ctx, "j1")
+	assert.Equal(context.Canceled, err)
+	err = c.Job.DoJobDeleteContext(ctx, "j1")
+	assert.Equal(context.Canceled, err)
+	_, err = c.Catalog.SearchCatalogContext(ctx, "landsat", nil)
+	assert.Equal(context.Canceled, err)
+	_, err = c.Algorithm.GetInfoForAllContext(ctx)
+	assert.Equal(context.Canceled, err)
+
+	_, err = c.Job.GetInfoForJob("j1")
+	assert.NoError(err)
+}
+
+func TestMemoryCoastlinesAndAlgorithms(t *testing.T) {
+	assert := assert.New(t)
+
+	c := NewMemoryClient()
+
+	line := &geojson.Feature{Type: "Feature", Geometry: &geojson.Geometry{Shape: &geojson.LineString{Coordinates: []geojson.Position{{1, 2}, {3, 4}}}}}
+	err := c.Coastline.(*MemoryCoastlines).SetCoastline("j1", &geojson.FeatureCollection{Type: "FeatureCollection", Features: []*geojson.Feature{line}})
+	assert.NoError(err)
+
+	fc, err := c.Coastline.GetCoastline("j1")
+	assert.NoError(err)
+	assert.Len(fc.Features, 1)
+
+	dir, err := ioutil.TempDir("", "bf-client")
+	assert.NoError(err)
+	defer os.RemoveAll(dir)
+	result, err := c.Coastline.DoDownload("j1", &DownloadOptions{OutputDir: dir})
+	assert.NoError(err)
+	assert.Equal(filepath.Join(dir, "j1.geojson"), result.File)
+	_, err = c.Coastline.DoDownload("j1", &DownloadOptions{OutputDir: dir})
+	assert.Error(err)
+	result, err = c.Coastline.DoDownload("j1", &DownloadOptions{OutputDir: dir, Existing: ExistingSkip})
+	assert.NoError(err)
+	assert.True(result.Skipped)
+
+	c.Algorithm.(*MemoryAlgorithms).AddAlgorithm(&AlgorithmInfo{ServiceId: "a1", Name: "NDWI_PY"})
+	alg, err := c.Algorithm.GetInfoForOne("a1")
+	assert.NoError(err)
+	assert.Equal("NDWI_PY", alg.Name)
+	_, err = c.Algorithm.GetInfoForOne("a2")
+	assert.True(errors.Is(err, ErrNotFound))
+}
@@ -0,0 +1,205 @@
+/* Copyright 2017, RadiantBlue Technologies, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const catalogServer = "bf-ia-broker"
+
+func init() {
+	RegisterProvider("planet", &ProviderType{
+		New: func(cfg *Config) (CatalogProvider, error) {
+			return NewPlanetProvider(cfg)
+		},
+		Fields:    []string{"planet_key"},
+		URLFields: []string{"broker_url"},
+	})
+}
+
+// PlanetProvider finds Planet's scenes through the bf-ia-broker, which
+// needs the Planet key.
+type PlanetProvider struct {
+	url        string // "https://bf-ia-broker.int.geointservices.io", or as configured
+	credential Credential
+	httpClient *http.Client
+}
+
+// NewPlanetProvider builds the provider from the config's BrokerURL (or
+// Domain), PlanetKey and CatalogAuth or CatalogCredential.
+func NewPlanetProvider(cfg *Config) (*PlanetProvider, error) {
+
+	credential := cfg.CatalogCredential
+	if credential == nil {
+		err := cfg.require("planet_key")
+		if err != nil {
+			return nil, err
+		}
+		credential, err = planetCredential(cfg.CatalogAuth, cfg.PlanetKey)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	url, err := cfg.serviceURL(cfg.BrokerURL, "broker_url", catalogServer)
+	if err != nil {
+		return nil, err
+	}
+
+	httpClient := cfg.HTTPClient
+	if httpClient == nil {
+		httpClient = cfg.withHTTPClient().HTTPClient
+	}
+
+	return &PlanetProvider{
+		url:        url,
+		credential: credential,
+		httpClient: httpClient,
+	}, nil
+}
+
+// one page of a broker search: the features plus a link to the next page
+type catalogPage struct {
+	Catalog
+	Links struct {
+		Next string `json:"_next"`
+	} `json:"_links"`
+}
+
+func (p *PlanetProvider) SceneInfo(ctx context.Context, id string) (*CatalogFeature, error) {
+	sensor, scene, err := splitId(id)
+	if err != nil {
+		return nil, err
+	}
+
+	path := "/planet/" + sensor + "/" + scene
+
+	url := fmt.Sprintf("%s%s", p.url, path)
+
+	jsn, err := doHttpGetJSON(ctx, p.httpClient, url, p.credential, 200)
+	if err != nil {
+		return nil, err
+	}
+
+	obj := &CatalogFeature{}
+	err = json.Unmarshal([]byte(jsn), obj)
+	if err != nil {
+		return nil, err
+	}
+
+	return obj, nil
+}
+
+// Search follows the broker's "next" links until every page has been read,
+// the query's limit is reached, or a link repeats.
+func (p *PlanetProvider) Search(ctx context.Context, catalog string, query *SearchQuery) (*Catalog, error) {
+	params, err := query.values()
+	if err != nil {
+		return nil, err
+	}
+
+	base, err := url.Parse(p.url + "/planet/discover/" + url.PathEscape(catalog))
+	if err != nil {
+		return nil, err
+	}
+	base.RawQuery = params.Encode()
+
+	result := &Catalog{Type: "FeatureCollection", Features: []*CatalogFeature{}}
+
+	next := base.String()
+	seen := map[string]bool{}
+	for next != "" && !seen[next] {
+		seen[next] = true
+		jsn, err := doHttpGetJSON(ctx, p.httpClient, next, p.credential, 200)
+		if err != nil {
+			return nil, err
+		}
+
+		page := &catalogPage{}
+		err = json.Unmarshal([]byte(jsn), page)
+		if err != nil {
+			return nil, err
+		}
+
+		result.Features = append(result.Features, page.Features...)
+		if query.Limit > 0 && len(result.Features) >= query.Limit {
+			result.Features = result.Features[:query.Limit]
+			break
+		}
+
+		next, err = p.nextPage(base, page.Links.Next)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+// resolves a "next" link against the search URL. The broker knows nothing
+// of any path prefix in front of it, so one is added to links from the
+// server root. The credential goes along with each request, so a key the
+// broker echoes back in the link is dropped.
+func (p *PlanetProvider) nextPage(base *url.URL, link string) (string, error) {
+	if link == "" {
+		return "", nil
+	}
+
+	ref, err := url.Parse(link)
+	if err != nil {
+		return "", err
+	}
+
+	root, err := url.Parse(p.url)
+	if err != nil {
+		return "", err
+	}
+	prefix := root.Path
+	if ref.Host == "" && prefix != "" && strings.HasPrefix(ref.Path, "/") && !strings.HasPrefix(ref.Path, prefix+"/") {
+		ref.Path = prefix + ref.Path
+	}
+
+	next := base.ResolveReference(ref)
+	params := next.Query()
+	if _, ok := params[planetKeyParam]; ok {
+		params.Del(planetKeyParam)
+		next.RawQuery = params.Encode()
+	}
+
+	return next.String(), nil
+}
+
+// ListAssets returns the scene's bands, which Planet serves from signed
+// URLs needing no key.
+func (p *PlanetProvider) ListAssets(ctx context.Context, id string) ([]*Asset, error) {
+	info, err := p.SceneInfo(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return bandAssets(info), nil
+}
+
+// DownloadAsset streams the asset to the file, resuming from a ".part"
+// file left by an interrupted download.
+func (p *PlanetProvider) DownloadAsset(ctx context.Context, asset *Asset, path string, progress ProgressReporter) (int64, error) {
+	return doHttpDownloadFile(ctx, p.httpClient, asset.URL, "", path, progress)
+}
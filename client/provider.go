@@ -0,0 +1,128 @@
+/* Copyright 2017, RadiantBlue Technologies, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// CatalogProvider is a source of imagery: the broker's Planet catalogs,
+// or another. A CatalogClient answers the catalog calls with one, so a new
+// source needs only a provider, registered by name.
+type CatalogProvider interface {
+	// SceneInfo returns the scene with the id "<catalog>:<scene>".
+	SceneInfo(ctx context.Context, id string) (*CatalogFeature, error)
+
+	// Search returns the scenes in the catalog matching the query, reading
+	// every page until the query's limit is reached. The query has been
+	// checked already.
+	Search(ctx context.Context, catalog string, query *SearchQuery) (*Catalog, error)
+
+	// ListAssets returns the files a scene is downloaded as.
+	ListAssets(ctx context.Context, id string) ([]*Asset, error)
+
+	// DownloadAsset writes the asset to the file at path, whose directory
+	// exists, and returns its size.
+	DownloadAsset(ctx context.Context, asset *Asset, path string, progress ProgressReporter) (int64, error)
+}
+
+// Asset is a file of a scene, such as one band.
+type Asset struct {
+	Name string // the band, e.g. "coastal"
+	URL  string // where the provider gets it; its last element is the {file} of a download template
+}
+
+// the scene's bands as assets, sorted by name, for providers that list
+// them in the scene's properties
+func bandAssets(f *CatalogFeature) []*Asset {
+	assets := []*Asset{}
+	if f.Properties == nil {
+		return assets
+	}
+	for name, url := range f.Properties.Bands {
+		assets = append(assets, &Asset{Name: name, URL: url})
+	}
+	sort.Slice(assets, func(i, j int) bool { return assets[i].Name < assets[j].Name })
+	return assets
+}
+
+//---------------------------------------------------------------------
+
+// ProviderFactory builds a provider from the config.
+type ProviderFactory func(cfg *Config) (CatalogProvider, error)
+
+// ProviderType is a kind of provider, as registered by name.
+type ProviderType struct {
+	New ProviderFactory
+
+	// The settings the provider needs, checked before New is called when
+	// building from the settings. If any of URLFields is not set, domain
+	// is needed too.
+	Fields    []string
+	URLFields []string
+}
+
+// DefaultProvider is used when the provider setting is not given.
+const DefaultProvider = "planet"
+
+var providers = map[string]*ProviderType{}
+
+// RegisterProvider makes a kind of provider available by name, for the
+// provider setting and the catalog command's --provider flag. A later
+// registration under the same name replaces the earlier one.
+func RegisterProvider(name string, t *ProviderType) {
+	providers[name] = t
+}
+
+// ProviderNames returns the names of the registered providers, sorted.
+func ProviderNames() []string {
+	names := []string{}
+	for name := range providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func lookupProvider(name string) (*ProviderType, error) {
+	if name == "" {
+		name = DefaultProvider
+	}
+	t, ok := providers[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown catalog provider '%s': expected one of %s", name, strings.Join(ProviderNames(), ", "))
+	}
+	return t, nil
+}
+
+// loads the settings as loadConfig does, requiring the fields of the
+// provider the settings name as well
+func loadCatalogConfig(fields []string, urlFields ...string) (*Config, error) {
+	settings, err := LoadSettings()
+	if err != nil {
+		return nil, err
+	}
+	t, err := lookupProvider(settings.Get("provider"))
+	if err != nil {
+		return nil, err
+	}
+	fields = append(append([]string{}, fields...), t.Fields...)
+	urlFields = append(append([]string{}, urlFields...), t.URLFields...)
+	return loadConfig(fields, urlFields...)
+}
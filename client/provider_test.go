@@ -0,0 +1,98 @@
+/* Copyright 2017, RadiantBlue Technologies, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// a provider with one scene, whose one band holds the scene's id
+type fakeProvider struct {
+	searched []string
+}
+
+func (p *fakeProvider) SceneInfo(ctx context.Context, id string) (*CatalogFeature, error) {
+	return &CatalogFeature{Type: "Feature", Id: id, Properties: &PropertiesInfo{Bands: map[string]string{"red": "fake:///red.TIF"}}}, nil
+}
+
+func (p *fakeProvider) Search(ctx context.Context, catalog string, query *SearchQuery) (*Catalog, error) {
+	p.searched = append(p.searched, catalog)
+	return &Catalog{Type: "FeatureCollection", Features: []*CatalogFeature{{Id: "one"}, {Id: "two"}}}, nil
+}
+
+func (p *fakeProvider) ListAssets(ctx context.Context, id string) ([]*Asset, error) {
+	return []*Asset{{Name: "red", URL: "fake:///" + id + "/red.TIF"}}, nil
+}
+
+func (p *fakeProvider) DownloadAsset(ctx context.Context, asset *Asset, path string, progress ProgressReporter) (int64, error) {
+	return 3, ioutil.WriteFile(path, []byte("red"), 0644)
+}
+
+func TestProviderRegistry(t *testing.T) {
+	assert := assert.New(t)
+
+	fake := &fakeProvider{}
+	RegisterProvider("fake", &ProviderType{
+		New:    func(cfg *Config) (CatalogProvider, error) { return fake, nil },
+		Fields: []string{"auth"},
+	})
+	defer delete(providers, "fake")
+
+	assert.Equal([]string{"fake", "local", "planet", "stac"}, ProviderNames())
+
+	// chosen by the settings, needing only its own fields
+	withBeachfrontrc(t, `{"domain": "example.com", "auth": "abc"}`)
+	SetOverride("provider", "fake")
+	c, err := NewCatalogClient()
+	assert.NoError(err)
+	assert.True(c.Provider() == fake)
+
+	catalog, err := c.SearchCatalog("anything", &SearchQuery{Limit: 1})
+	assert.NoError(err)
+	assert.Len(catalog.Features, 2) // the limit is the provider's to apply
+	assert.Equal([]string{"anything"}, fake.searched)
+
+	dir, err := ioutil.TempDir("", "bf-client")
+	assert.NoError(err)
+	defer os.RemoveAll(dir)
+	results, err := c.DoCatalogSceneDownload("fake:s1", &DownloadOptions{OutputDir: dir})
+	assert.NoError(err)
+	assert.Len(results, 1)
+	assert.Equal(filepath.Join(dir, "red.TIF"), results[0].File)
+
+	// the planet provider still needs the Planet key
+	SetOverride("provider", "planet")
+	_, err = NewCatalogClient()
+	assert.Error(err)
+	assert.Contains(err.Error(), "planet_key")
+
+	SetOverride("provider", "nope")
+	_, err = NewCatalogClient()
+	assert.Error(err)
+	assert.Contains(err.Error(), "expected one of fake, local, planet, stac")
+
+	settings, err := LoadSettings()
+	assert.NoError(err)
+	problems := settings.Validate()
+	assert.NotEmpty(problems)
+	assert.Contains(problems[len(problems)-1].Error(), "unknown catalog provider 'nope'")
+}
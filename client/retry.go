@@ -0,0 +1,167 @@
+/* Copyright 2017, RadiantBlue Technologies, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy says how often, and how patiently, requests are retried.
+// Only reads (GET, HEAD, OPTIONS) are retried, and only after a network
+// error or a 429, 502, 503 or 504 response. A DELETE is not: one that
+// reached the server before its response was lost would be retried into a
+// 404, reporting a deleted job as not deleted.
+//
+// A Config's Retry sets the policy of the clients built from it, and
+// WithRetryPolicy that of the requests made with a context.
+type RetryPolicy struct {
+	MaxAttempts int           // including the first, so 1 means no retries
+	BaseDelay   time.Duration // before the first retry, doubling after that
+	MaxDelay    time.Duration // limit on any one wait, Retry-After included
+}
+
+// DefaultRetryPolicy is used when a Config has no policy of its own.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 4,
+	BaseDelay:   500 * time.Millisecond,
+	MaxDelay:    30 * time.Second,
+}
+
+type retryPolicyKey struct{}
+
+// WithRetryPolicy returns a context whose requests follow the policy
+// rather than the client's own, e.g. to turn retries off for one call.
+func WithRetryPolicy(ctx context.Context, policy *RetryPolicy) context.Context {
+	return context.WithValue(ctx, retryPolicyKey{}, policy)
+}
+
+// the wait before the given retry (1 for the first): full jitter over the
+// upper half of the exponential delay, or the server's Retry-After
+func (p *RetryPolicy) delay(retry int, retryAfter time.Duration) time.Duration {
+	base := durationOr(p.BaseDelay, DefaultRetryPolicy.BaseDelay)
+	max := durationOr(p.MaxDelay, DefaultRetryPolicy.MaxDelay)
+
+	d := retryAfter
+	if d <= 0 {
+		d = base
+		for i := 1; i < retry && d < max; i++ {
+			d *= 2
+		}
+		d = d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+	}
+	if d > max {
+		d = max
+	}
+	return d
+}
+
+func isRetryableMethod(method string) bool {
+	switch method {
+	case "GET", "HEAD", "OPTIONS":
+		return true
+	}
+	return false
+}
+
+func isRetryableStatus(status int) bool {
+	switch status {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// Retry-After is either a number of seconds or an HTTP date
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(value); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(value); err == nil && t.After(now) {
+		return t.Sub(now)
+	}
+	return 0
+}
+
+//---------------------------------------------------------------------
+
+// retryTransport retries requests as its policy, or the one in the
+// request's context, allows.
+type retryTransport struct {
+	base   http.RoundTripper
+	policy *RetryPolicy
+}
+
+func newRetryTransport(base http.RoundTripper, policy *RetryPolicy) *retryTransport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if policy == nil {
+		policy = &DefaultRetryPolicy
+	}
+	return &retryTransport{base: base, policy: policy}
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	policy := t.policy
+	if p, ok := ctx.Value(retryPolicyKey{}).(*RetryPolicy); ok && p != nil {
+		policy = p
+	}
+	attempts := policy.MaxAttempts
+	if attempts < 1 || !isRetryableMethod(req.Method) {
+		attempts = 1
+	}
+
+	for attempt := 1; ; attempt++ {
+		resp, err := t.base.RoundTrip(req)
+
+		var retryAfter time.Duration
+		switch {
+		case err != nil:
+			if ctx.Err() != nil || attempt == attempts {
+				return nil, err
+			}
+			debugf("%s %s: attempt %d of %d failed: %s", req.Method, req.URL.Path, attempt, attempts, err.Error())
+		case isRetryableStatus(resp.StatusCode) && attempt < attempts:
+			debugf("%s %s: attempt %d of %d failed with status %d", req.Method, req.URL.Path, attempt, attempts, resp.StatusCode)
+			retryAfter = parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+			io.Copy(ioutil.Discard, io.LimitReader(resp.Body, 64*1024))
+			resp.Body.Close()
+		default:
+			if attempt > 1 {
+				debugf("%s %s: attempt %d of %d done, status %d", req.Method, req.URL.Path, attempt, attempts, resp.StatusCode)
+			}
+			return resp, nil
+		}
+
+		wait := policy.delay(attempt, retryAfter)
+		debugf("%s %s: retrying in %s", req.Method, req.URL.Path, wait)
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
@@ -0,0 +1,145 @@
+/* Copyright 2017, RadiantBlue Technologies, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/venicegeo/bf-client/bftest"
+)
+
+func TestRetryTransport(t *testing.T) {
+	assert := assert.New(t)
+
+	failures := 0
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts <= failures {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"ok": true}`))
+	}))
+	defer server.Close()
+
+	policy := &RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond}
+	httpClient := &http.Client{Transport: newRetryTransport(nil, policy)}
+	ctx := context.Background()
+
+	// two failures, then success
+	failures = 2
+	body, err := doHttpGetJSON(ctx, httpClient, server.URL, nil, 200)
+	assert.NoError(err)
+	assert.Equal(`{"ok": true}`, body)
+	assert.Equal(3, attempts)
+
+	// more failures than attempts
+	attempts, failures = 0, 5
+	_, err = doHttpGetJSON(ctx, httpClient, server.URL, nil, 200)
+	assert.Error(err)
+	assert.Equal(3, attempts)
+
+	// not idempotent, so not retried
+	attempts = 0
+	_, err = doHttpPostJSONWithAuth(ctx, httpClient, server.URL, "abc", "{}", 201)
+	assert.Error(err)
+	assert.Equal(1, attempts)
+
+	// nor is a delete, which may have been done before the failure
+	attempts = 0
+	_, err = doHttpDeleteWithAuth(ctx, httpClient, server.URL, "abc", 200)
+	assert.Error(err)
+	assert.Equal(1, attempts)
+
+	// overridden for the one call
+	attempts = 0
+	_, err = doHttpGetJSON(WithRetryPolicy(ctx, &RetryPolicy{MaxAttempts: 1}), httpClient, server.URL, nil, 200)
+	assert.Error(err)
+	assert.Equal(1, attempts)
+}
+
+func TestRetryPolicyOfConfig(t *testing.T) {
+	assert := assert.New(t)
+
+	server := bftest.NewServer()
+	defer server.Close()
+	server.AddAlgorithm(&bftest.Algorithm{Name: "NDWI_PY"})
+
+	cfg := &Config{APIURL: server.URL, BrokerURL: server.URL, Auth: bftest.Auth, PlanetKey: bftest.PlanetKey,
+		Retry: &RetryPolicy{MaxAttempts: 2, BaseDelay: time.Millisecond}}
+	client, err := NewClientWithConfig(cfg)
+	assert.NoError(err)
+	c := client.Algorithm
+
+	// the client's policy, from its config...
+	server.Fail("/v0/algorithm", http.StatusBadGateway, 1)
+	_, err = c.GetInfoForAll()
+	assert.NoError(err)
+
+	// ...unless the context has one of its own
+	server.Fail("/v0/algorithm", http.StatusBadGateway, 1)
+	_, err = c.GetInfoForAllContext(WithRetryPolicy(context.Background(), &RetryPolicy{MaxAttempts: 1}))
+	assert.Error(err)
+
+	server.Fail("/v0/algorithm", http.StatusBadGateway, 2)
+	_, err = c.GetInfoForAll()
+	assert.Error(err)
+}
+
+func TestRetryNetworkError(t *testing.T) {
+	assert := assert.New(t)
+
+	server := httptest.NewServer(http.NotFoundHandler())
+	url := server.URL
+	server.Close()
+
+	policy := &RetryPolicy{MaxAttempts: 3, BaseDelay: time.Hour}
+	httpClient := &http.Client{Transport: newRetryTransport(nil, policy)}
+
+	// cancelled while waiting to retry
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := doHttpGetJSON(ctx, httpClient, url, nil, 200)
+	assert.Error(err)
+	assert.Equal(context.DeadlineExceeded, ctx.Err())
+}
+
+func TestRetryDelay(t *testing.T) {
+	assert := assert.New(t)
+
+	policy := &RetryPolicy{MaxAttempts: 5, BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+
+	for retry, max := range []time.Duration{100, 200, 400, 800, 1000, 1000} {
+		max *= time.Millisecond
+		d := policy.delay(retry+1, 0)
+		assert.True(d >= max/2 && d <= max, "retry %d: %s", retry+1, d)
+	}
+
+	assert.Equal(700*time.Millisecond, policy.delay(1, 700*time.Millisecond))
+	assert.Equal(time.Second, policy.delay(1, time.Minute))
+
+	now := time.Date(2017, 8, 31, 12, 0, 0, 0, time.UTC)
+	assert.Equal(120*time.Second, parseRetryAfter("120", now))
+	assert.Equal(30*time.Second, parseRetryAfter("Thu, 31 Aug 2017 12:00:30 GMT", now))
+	assert.Equal(time.Duration(0), parseRetryAfter("soon", now))
+}
@@ -0,0 +1,85 @@
+/* Copyright 2017, RadiantBlue Technologies, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+
+	"github.com/venicegeo/bf-client/geojson"
+)
+
+// The services a Client is made of. The HTTP clients implement them, as do
+// the in-memory ones in memory.go, so code that uses a Client can be tested
+// without a network.
+
+// CatalogService finds scenes in the broker's catalogs and downloads them.
+type CatalogService interface {
+	GetInfoForCatalogs() error
+	GetInfoForScene(id string) (*CatalogFeature, error)
+	GetInfoForSceneContext(ctx context.Context, id string) (*CatalogFeature, error)
+	GetInfoForCatalog(id string) (*Catalog, error)
+	GetInfoForCatalogContext(ctx context.Context, id string) (*Catalog, error)
+	SearchCatalog(id string, query *SearchQuery) (*Catalog, error)
+	SearchCatalogContext(ctx context.Context, id string, query *SearchQuery) (*Catalog, error)
+	DoCatalogSceneDownload(id string, opts *DownloadOptions) ([]*DownloadResult, error)
+	DoCatalogSceneDownloadContext(ctx context.Context, id string, opts *DownloadOptions) ([]*DownloadResult, error)
+}
+
+// JobService manages the user's jobs.
+type JobService interface {
+	GetInfoForJobs() (*JobList, error)
+	GetInfoForJobsContext(ctx context.Context) (*JobList, error)
+	GetInfoForJob(id string) (*Job, error)
+	GetInfoForJobContext(ctx context.Context, id string) (*Job, error)
+	DoJobSubmit(submission *JobSubmission) (*Job, error)
+	DoJobSubmitContext(ctx context.Context, submission *JobSubmission) (*Job, error)
+	FindJobs(filter *JobFilter) ([]*Job, error)
+	FindJobsContext(ctx context.Context, filter *JobFilter) ([]*Job, error)
+	DoJobDelete(id string) error
+	DoJobDeleteContext(ctx context.Context, id string) error
+	DoJobDeleteMany(ids []string) ([]string, error)
+	DoJobDeleteManyContext(ctx context.Context, ids []string) ([]string, error)
+	WaitForJob(id string, opts *WaitOptions) (*Job, error)
+	WaitForJobContext(ctx context.Context, id string, opts *WaitOptions) (*Job, error)
+}
+
+// CoastlineService fetches the coastlines found by jobs.
+type CoastlineService interface {
+	GetCoastline(id string) (*geojson.FeatureCollection, error)
+	GetCoastlineContext(ctx context.Context, id string) (*geojson.FeatureCollection, error)
+	DoDownload(id string, opts *DownloadOptions) (*DownloadResult, error)
+	DoDownloadContext(ctx context.Context, id string, opts *DownloadOptions) (*DownloadResult, error)
+}
+
+// AlgorithmService lists the algorithms jobs can run.
+type AlgorithmService interface {
+	GetInfoForAll() (*Algorithms, error)
+	GetInfoForAllContext(ctx context.Context) (*Algorithms, error)
+	GetInfoForOne(id string) (*AlgorithmInfo, error)
+	GetInfoForOneContext(ctx context.Context, id string) (*AlgorithmInfo, error)
+}
+
+var (
+	_ CatalogService   = (*CatalogClient)(nil)
+	_ JobService       = (*JobClient)(nil)
+	_ CoastlineService = (*CoastlineClient)(nil)
+	_ AlgorithmService = (*AlgorithmClient)(nil)
+
+	_ CatalogService   = (*MemoryCatalog)(nil)
+	_ JobService       = (*MemoryJobs)(nil)
+	_ CoastlineService = (*MemoryCoastlines)(nil)
+	_ AlgorithmService = (*MemoryAlgorithms)(nil)
+)
@@ -0,0 +1,169 @@
+/* Copyright 2017, RadiantBlue Technologies, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// The clients' settings are resolved in layers, each field taken from the
+// first of:
+//
+//   1. overrides, as given to SetOverrides (the CLI's flags)
+//   2. the environment, as $BEACHFRONT_<FIELD>, e.g. $BEACHFRONT_PLANET_KEY
+//   3. the selected profile of the .beachfrontrc file, if there is one
+//   4. Defaults
+//
+// so a CI job can run with no .beachfrontrc at all.
+
+// Source says which layer a setting came from.
+type Source string
+
+const (
+	SourceFlag    Source = "flag"
+	SourceEnv     Source = "env"
+	SourceFile    Source = "file"
+	SourceDefault Source = "default"
+)
+
+// Defaults holds the value of a field when no other layer sets it.
+var Defaults = map[string]string{}
+
+// the values given to SetOverrides
+var overrides = map[string]string{}
+
+// SetOverrides sets fields that take precedence over every other source.
+// Empty values are ignored, so unset flags can be passed straight through.
+func SetOverrides(values map[string]string) {
+	overrides = map[string]string{}
+	for k, v := range values {
+		if v != "" {
+			overrides[k] = v
+		}
+	}
+}
+
+// SetOverride sets one field to take precedence over every other source,
+// leaving the other overrides as they are, e.g. for a subcommand's flag.
+func SetOverride(field string, value string) {
+	if value != "" {
+		overrides[field] = value
+	}
+}
+
+// EnvVar returns the name of the environment variable for a field.
+func EnvVar(field string) string {
+	return "BEACHFRONT_" + strings.ToUpper(field)
+}
+
+// Setting is one resolved field.
+type Setting struct {
+	Value  string
+	Source Source
+	Origin string // the flag, variable or file it came from
+}
+
+// Settings is the resolved configuration shared by the clients.
+type Settings struct {
+	Profile string // the .beachfrontrc profile consulted
+	values  map[string]*Setting
+}
+
+// LoadSettings resolves every field from the layers above. Only a
+// .beachfrontrc that exists but cannot be read, or lacks the selected
+// profile, is an error: missing fields are reported by Require.
+func LoadSettings() (*Settings, error) {
+	s := &Settings{values: map[string]*Setting{}}
+
+	for field, value := range Defaults {
+		s.set(field, value, SourceDefault, "default")
+	}
+
+	path := BeachfrontrcPath()
+	rc, err := readBeachfrontrc()
+	switch {
+	case os.IsNotExist(err):
+		s.Profile = (&Beachfrontrc{}).profileName()
+	case err != nil:
+		return nil, err
+	default:
+		name, profile, err := rc.profile()
+		if err != nil {
+			return nil, err
+		}
+		s.Profile = name
+		for field, value := range profile {
+			s.set(field, value, SourceFile, fmt.Sprintf("%s [%s]", path, name))
+		}
+	}
+
+	for _, field := range AllFields() {
+		name := EnvVar(field)
+		s.set(field, os.Getenv(name), SourceEnv, "$"+name)
+	}
+
+	for field, value := range overrides {
+		s.set(field, value, SourceFlag, "--"+strings.Replace(field, "_", "-", -1))
+	}
+
+	return s, nil
+}
+
+func (s *Settings) set(field string, value string, source Source, origin string) {
+	if value == "" {
+		return
+	}
+	s.values[field] = &Setting{Value: value, Source: source, Origin: origin}
+}
+
+// Get returns the value of a field, or "" if no layer sets it.
+func (s *Settings) Get(field string) string {
+	if setting, ok := s.values[field]; ok {
+		return setting.Value
+	}
+	return ""
+}
+
+// Lookup returns the resolved field, or nil if no layer sets it.
+func (s *Settings) Lookup(field string) *Setting {
+	return s.values[field]
+}
+
+// Require returns the fields, failing if any one of them is not set.
+func (s *Settings) Require(fields []string) (map[string]string, error) {
+	results := map[string]string{}
+	for _, field := range fields {
+		value := s.Get(field)
+		if value == "" {
+			return nil, fmt.Errorf("Missing setting '%s': set --%s, $%s or '%s' in .beachfrontrc profile '%s'",
+				field, strings.Replace(field, "_", "-", -1), EnvVar(field), field, s.Profile)
+		}
+		results[field] = value
+	}
+	return results, nil
+}
+
+// Validate checks that every field is set and well formed, whatever its
+// source. It returns all of the problems found.
+func (s *Settings) Validate() []error {
+	values := map[string]string{}
+	for field, setting := range s.values {
+		values[field] = setting.Value
+	}
+	return validateFields("configuration", values)
+}
@@ -0,0 +1,132 @@
+/* Copyright 2017, RadiantBlue Technologies, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSettingsLayers(t *testing.T) {
+	assert := assert.New(t)
+
+	withBeachfrontrc(t, `{"domain": "file.example.com", "auth": "file-auth", "planet_key": "file-key"}`)
+	t.Setenv(EnvVar("auth"), "env-auth")
+	t.Setenv(EnvVar("planet_key"), "env-key")
+	SetOverrides(map[string]string{"planet_key": "flag-key", "domain": ""})
+
+	settings, err := LoadSettings()
+	assert.NoError(err)
+	assert.Equal("default", settings.Profile)
+
+	domain := settings.Lookup("domain")
+	assert.Equal("file.example.com", domain.Value)
+	assert.Equal(SourceFile, domain.Source)
+	assert.Equal(filepath.Join(os.Getenv("HOME"), ".beachfrontrc")+" [default]", domain.Origin)
+
+	auth := settings.Lookup("auth")
+	assert.Equal("env-auth", auth.Value)
+	assert.Equal(SourceEnv, auth.Source)
+	assert.Equal("$BEACHFRONT_AUTH", auth.Origin)
+
+	key := settings.Lookup("planet_key")
+	assert.Equal("flag-key", key.Value)
+	assert.Equal(SourceFlag, key.Source)
+	assert.Equal("--planet-key", key.Origin)
+
+	assert.Empty(settings.Validate())
+}
+
+func TestSettingsWithoutFile(t *testing.T) {
+	assert := assert.New(t)
+
+	withBeachfrontrc(t, "")
+	os.Remove(BeachfrontrcPath())
+
+	settings, err := LoadSettings()
+	assert.NoError(err)
+	_, err = settings.Require([]string{"domain"})
+	assert.Error(err)
+	assert.Len(settings.Validate(), 3)
+
+	t.Setenv(EnvVar("domain"), "int.example.com")
+	t.Setenv(EnvVar("auth"), "abc")
+
+	c, err := NewJobClient()
+	assert.NoError(err)
+	assert.Equal("https://bf-api.int.example.com", c.url)
+	assert.Equal("abc", c.auth)
+
+	_, err = NewCatalogClient()
+	assert.Error(err)
+
+	// no domain is needed when the URL is given
+	t.Setenv(EnvVar("domain"), "")
+	t.Setenv(EnvVar("api_url"), "http://localhost:8080/beachfront")
+	c, err = NewJobClient()
+	assert.NoError(err)
+	assert.Equal("http://localhost:8080/beachfront", c.url)
+
+	settings, err = LoadSettings()
+	assert.NoError(err)
+	_, err = NewClient()
+	assert.Error(err)
+	assert.Len(settings.Validate(), 2) // domain, planet_key
+
+	t.Setenv(EnvVar("broker_url"), "broker:9090")
+	settings, err = LoadSettings()
+	assert.NoError(err)
+	assert.Len(settings.Validate(), 2) // planet_key, broker_url
+}
+
+func TestSettingsDefaults(t *testing.T) {
+	assert := assert.New(t)
+
+	withBeachfrontrc(t, `{"domain": "file.example.com"}`)
+	Defaults["domain"] = "default.example.com"
+	Defaults["auth"] = "default-auth"
+	defer func() { Defaults = map[string]string{} }()
+
+	settings, err := LoadSettings()
+	assert.NoError(err)
+	assert.Equal("file.example.com", settings.Get("domain"))
+	assert.Equal(SourceDefault, settings.Lookup("auth").Source)
+	assert.Nil(settings.Lookup("planet_key"))
+}
+
+func TestSettingsXDG(t *testing.T) {
+	assert := assert.New(t)
+
+	withBeachfrontrc(t, "")
+	os.Remove(BeachfrontrcPath())
+
+	dir := filepath.Join(os.Getenv("HOME"), "xdg")
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	assert.NoError(os.MkdirAll(filepath.Join(dir, "beachfront"), 0700))
+	path := filepath.Join(dir, "beachfront", "beachfrontrc")
+	assert.NoError(ioutil.WriteFile(path, []byte(`{"domain": "xdg.example.com"}`), 0600))
+
+	assert.Equal(path, BeachfrontrcPath())
+
+	settings, err := LoadSettings()
+	assert.NoError(err)
+	assert.Equal("xdg.example.com", settings.Get("domain"))
+	assert.Equal(path+" [default]", settings.Lookup("domain").Origin)
+}
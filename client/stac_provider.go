@@ -0,0 +1,348 @@
+/* Copyright 2017, RadiantBlue Technologies, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/venicegeo/bf-client/geojson"
+)
+
+func init() {
+	RegisterProvider("stac", &ProviderType{
+		New: func(cfg *Config) (CatalogProvider, error) {
+			return NewSTACProvider(cfg)
+		},
+		Fields: []string{"stac_url"},
+	})
+}
+
+// the most items asked for in one page of a search
+const stacPageSize = 100
+
+// STACProvider finds scenes through a STAC API's item search. A catalog is
+// a STAC collection, so the scene "landsat-c2-l2:LC08_..." is the item
+// LC08_... in the collection landsat-c2-l2, and its bands are the item's
+// data assets.
+type STACProvider struct {
+	url        string // "https://earth-search.aws.element84.com/v1", or as configured
+	httpClient *http.Client
+}
+
+// NewSTACProvider builds the provider from the config's STACURL.
+func NewSTACProvider(cfg *Config) (*STACProvider, error) {
+	err := cfg.require("stac_url")
+	if err != nil {
+		return nil, err
+	}
+
+	url, err := parseBaseURL(cfg.STACURL)
+	if err != nil {
+		return nil, fmt.Errorf("config has a malformed stac_url: %s", err.Error())
+	}
+
+	httpClient := cfg.HTTPClient
+	if httpClient == nil {
+		httpClient = cfg.withHTTPClient().HTTPClient
+	}
+
+	return &STACProvider{
+		url:        url,
+		httpClient: httpClient,
+	}, nil
+}
+
+//---------------------------------------------------------------------
+
+// the parts of a STAC Item that make a CatalogFeature
+type stacItem struct {
+	Type        string // "Feature"
+	STACVersion string `json:"stac_version"`
+	Id          string
+	Collection  string
+	Geometry    *geojson.Geometry
+	Bbox        []float64 // 2D, or 3D with the heights after each corner
+	Properties  *stacProperties
+	Links       []*stacLink
+	Assets      map[string]*stacAsset
+}
+
+type stacProperties struct {
+	Datetime      string  `json:"datetime"` // null for a range
+	StartDatetime string  `json:"start_datetime"`
+	CloudCover    float64 `json:"eo:cloud_cover"`
+	GSD           float64 `json:"gsd"`
+	Platform      string
+	Instruments   []string
+}
+
+type stacLink struct {
+	Rel    string
+	Href   string
+	Method string // "GET" if empty
+}
+
+type stacAsset struct {
+	Href  string
+	Type  string
+	Roles []string
+}
+
+// one page of a search
+type stacItemCollection struct {
+	Features []*stacItem
+	Links    []*stacLink
+}
+
+// the first link with the relation, or nil
+func findLink(links []*stacLink, rel string) *stacLink {
+	for _, link := range links {
+		if link.Rel == rel {
+			return link
+		}
+	}
+	return nil
+}
+
+// whether the asset is one of the item's bands: it has the "data" role,
+// or, having no roles, is a GeoTIFF
+func (a *stacAsset) isBand() bool {
+	if len(a.Roles) == 0 {
+		return isGeoTIFF(a)
+	}
+	for _, role := range a.Roles {
+		if role == "data" {
+			return true
+		}
+	}
+	return false
+}
+
+func isGeoTIFF(a *stacAsset) bool {
+	if a.Type != "" {
+		return strings.HasPrefix(a.Type, "image/tiff")
+	}
+	ext := strings.ToLower(path.Ext(a.Href))
+	return ext == ".tif" || ext == ".tiff"
+}
+
+// where the item's relative links are from: its self link, or else the
+// URL it was read from
+func (item *stacItem) baseURL(from *url.URL) *url.URL {
+	if self := findLink(item.Links, "self"); self != nil && self.Href != "" {
+		u, err := from.Parse(self.Href)
+		if err == nil {
+			return u
+		}
+	}
+	return from
+}
+
+// the item as a scene, with the band URLs resolved against base
+func (item *stacItem) feature(base *url.URL) (*CatalogFeature, error) {
+	f := &CatalogFeature{
+		Type:     "Feature",
+		Id:       item.Id,
+		Geometry: item.Geometry,
+		Properties: &PropertiesInfo{
+			Bands: map[string]string{},
+		},
+	}
+
+	switch len(item.Bbox) {
+	case 4:
+		copy(f.Bbox[:], item.Bbox)
+	case 6:
+		f.Bbox = [4]float64{item.Bbox[0], item.Bbox[1], item.Bbox[3], item.Bbox[4]}
+	}
+
+	if p := item.Properties; p != nil {
+		f.Properties.AcquiredDate = p.Datetime
+		if f.Properties.AcquiredDate == "" {
+			f.Properties.AcquiredDate = p.StartDatetime
+		}
+		f.Properties.CloudCover = p.CloudCover
+		f.Properties.Resolution = p.GSD
+		f.Properties.SensorName = p.Platform
+		if f.Properties.SensorName == "" {
+			f.Properties.SensorName = strings.Join(p.Instruments, ",")
+		}
+	}
+
+	for name, asset := range item.Assets {
+		if asset == nil || !asset.isBand() {
+			continue
+		}
+		u, err := base.Parse(asset.Href)
+		if err != nil {
+			return nil, fmt.Errorf("item %s has a malformed href for %s: %s", item.Id, name, asset.Href)
+		}
+		f.Properties.Bands[name] = u.String()
+		if isGeoTIFF(asset) {
+			f.Properties.FileFormat = "geotiff"
+		}
+	}
+
+	return f, nil
+}
+
+//---------------------------------------------------------------------
+
+// the search URL for the query: collections, bbox, datetime, a query on
+// eo:cloud_cover, and a page size
+func (p *STACProvider) searchURL(collection string, query *SearchQuery) string {
+	params := url.Values{}
+	params.Set("collections", collection)
+
+	if len(query.Bbox) > 0 {
+		parts := []string{}
+		for _, v := range query.Bbox {
+			parts = append(parts, strconv.FormatFloat(v, 'f', -1, 64))
+		}
+		params.Set("bbox", strings.Join(parts, ","))
+	}
+
+	if !query.AcquiredAfter.IsZero() || !query.AcquiredBefore.IsZero() {
+		params.Set("datetime", stacTime(query.AcquiredAfter)+"/"+stacTime(query.AcquiredBefore))
+	}
+
+	if query.MaxCloudCover != nil {
+		params.Set("query", fmt.Sprintf(`{"eo:cloud_cover":{"lte":%s}}`, strconv.FormatFloat(*query.MaxCloudCover, 'f', -1, 64)))
+	}
+
+	limit := stacPageSize
+	if query.Limit > 0 && query.Limit < limit {
+		limit = query.Limit
+	}
+	params.Set("limit", strconv.Itoa(limit))
+
+	return p.url + "/search?" + params.Encode()
+}
+
+// one end of a datetime interval, ".." if open
+func stacTime(t time.Time) string {
+	if t.IsZero() {
+		return ".."
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// reads the item or page at the URL into obj, returning the parsed URL
+// for resolving the links in it
+func (p *STACProvider) get(ctx context.Context, rawurl string, obj interface{}) (*url.URL, error) {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return nil, err
+	}
+
+	jsn, err := doHttpGetJSON(ctx, p.httpClient, rawurl, nil, 200)
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal([]byte(jsn), obj)
+	if err != nil {
+		return nil, err
+	}
+
+	return u, nil
+}
+
+func (p *STACProvider) SceneInfo(ctx context.Context, id string) (*CatalogFeature, error) {
+	collection, item, err := splitId(id)
+	if err != nil {
+		return nil, err
+	}
+
+	path := "/collections/" + url.PathEscape(collection) + "/items/" + url.PathEscape(item)
+
+	url := fmt.Sprintf("%s%s", p.url, path)
+
+	obj := &stacItem{}
+	from, err := p.get(ctx, url, obj)
+	if err != nil {
+		return nil, err
+	}
+
+	return obj.feature(obj.baseURL(from))
+}
+
+// Search follows the search's "next" links until every page has been read,
+// the query's limit is reached, or a link repeats. Only links to be followed with a GET
+// are understood.
+func (p *STACProvider) Search(ctx context.Context, catalog string, query *SearchQuery) (*Catalog, error) {
+	result := &Catalog{Type: "FeatureCollection", Features: []*CatalogFeature{}}
+
+	next := p.searchURL(catalog, query)
+	seen := map[string]bool{}
+	for next != "" && !seen[next] {
+		seen[next] = true
+		page := &stacItemCollection{}
+		from, err := p.get(ctx, next, page)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, item := range page.Features {
+			f, err := item.feature(item.baseURL(from))
+			if err != nil {
+				return nil, err
+			}
+			result.Features = append(result.Features, f)
+		}
+		if query.Limit > 0 && len(result.Features) >= query.Limit {
+			result.Features = result.Features[:query.Limit]
+			break
+		}
+
+		next = ""
+		if link := findLink(page.Links, "next"); link != nil {
+			if link.Method != "" && link.Method != "GET" {
+				return nil, fmt.Errorf("STAC search paging with %s is not supported", link.Method)
+			}
+			u, err := from.Parse(link.Href)
+			if err != nil {
+				return nil, err
+			}
+			next = u.String()
+		}
+	}
+
+	return result, nil
+}
+
+// ListAssets returns the item's data assets, as its bands.
+func (p *STACProvider) ListAssets(ctx context.Context, id string) ([]*Asset, error) {
+	info, err := p.SceneInfo(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return bandAssets(info), nil
+}
+
+// DownloadAsset streams the asset to the file, resuming from a ".part"
+// file left by an interrupted download. Assets are fetched with no key.
+func (p *STACProvider) DownloadAsset(ctx context.Context, asset *Asset, path string, progress ProgressReporter) (int64, error) {
+	return doHttpDownloadFile(ctx, p.httpClient, asset.URL, "", path, progress)
+}
@@ -0,0 +1,171 @@
+/* Copyright 2017, RadiantBlue Technologies, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/venicegeo/bf-client/bftest"
+)
+
+func TestSTACProvider(t *testing.T) {
+	assert := assert.New(t)
+
+	_, server := newTestClient(t)
+
+	// chosen by the settings, needing only the STAC URL
+	withBeachfrontrc(t, `{}`)
+	SetOverride("stac_url", server.STACURL())
+	SetOverride("provider", "stac")
+	c, err := NewCatalogClient()
+	assert.NoError(err)
+	assert.IsType(&STACProvider{}, c.Provider())
+
+	feature, err := c.GetInfoForScene(testSceneId)
+	assert.NoError(err)
+	assert.Equal("LC81260322017212LGN00", feature.Id)
+	assert.Equal([4]float64{115.6, 38.5, 118.3, 40.6}, feature.Bbox)
+	assert.Equal("Polygon", feature.Geometry.Type())
+	assert.Equal("2017-07-31T02:58:35Z", feature.Properties.AcquiredDate)
+	assert.Equal(3.4, feature.Properties.CloudCover)
+	assert.Equal(30.0, feature.Properties.Resolution)
+	assert.Equal("Landsat8", feature.Properties.SensorName)
+	assert.Equal("geotiff", feature.Properties.FileFormat)
+	assert.Len(feature.Properties.Bands, 3) // not the thumbnail
+	assert.Contains(feature.Properties.Bands["coastal"], "/bands/landsat/LC81260322017212LGN00/")
+
+	_, err = c.GetInfoForScene("landsat:LC80000000000000LGN00")
+	assert.True(errors.Is(err, ErrNotFound))
+	assert.Contains(err.Error(), "item not found")
+
+	dir, err := ioutil.TempDir("", "bf-client")
+	assert.NoError(err)
+	defer os.RemoveAll(dir)
+	results, err := c.DoCatalogSceneDownload(testSceneId, &DownloadOptions{OutputDir: dir})
+	assert.NoError(err)
+	assert.Len(results, 3)
+	byts, err := ioutil.ReadFile(filepath.Join(dir, "LC81260322017212LGN00_coastal.TIF"))
+	assert.NoError(err)
+	assert.Equal("B1", string(byts))
+}
+
+func TestSTACProviderSearch(t *testing.T) {
+	assert := assert.New(t)
+
+	_, server := newTestClient(t)
+
+	day := time.Date(2017, 8, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 9; i++ {
+		server.AddScene(&bftest.Scene{
+			Catalog:      "landsat",
+			Id:           fmt.Sprintf("LC8126033201721%dLGN00", i),
+			AcquiredDate: day.AddDate(0, 0, i),
+			CloudCover:   float64(i * 5),
+			Bbox:         [4]float64{115, 38, 118, 40},
+		})
+	}
+	server.AddScene(&bftest.Scene{Catalog: "sentinel", Id: "S2A", AcquiredDate: day})
+	server.SetPageSize(2)
+
+	provider, err := NewSTACProvider(&Config{STACURL: server.STACURL(), Retry: &RetryPolicy{MaxAttempts: 1}})
+	assert.NoError(err)
+	c := NewCatalogClientWithProvider(provider)
+
+	// every page is read: five of those, and the scene the server starts with...
+	catalog, err := c.SearchCatalog("landsat", &SearchQuery{MaxCloudCover: CloudCover(20)})
+	assert.NoError(err)
+	assert.Len(catalog.Features, 6)
+	for _, f := range catalog.Features {
+		assert.True(f.Properties.CloudCover <= 20)
+	}
+
+	// ...until the limit is reached
+	catalog, err = c.SearchCatalog("landsat", &SearchQuery{MaxCloudCover: CloudCover(20), Limit: 3})
+	assert.NoError(err)
+	assert.Len(catalog.Features, 3)
+
+	// zero is cloud-free, not any
+	catalog, err = c.SearchCatalog("landsat", &SearchQuery{MaxCloudCover: CloudCover(0)})
+	assert.NoError(err)
+	assert.Len(catalog.Features, 1)
+
+	catalog, err = c.SearchCatalog("landsat", &SearchQuery{
+		AcquiredAfter: day.AddDate(0, 0, 7),
+		Bbox:          []float64{117, 39, 120, 41},
+	})
+	assert.NoError(err)
+	assert.Len(catalog.Features, 2)
+
+	catalog, err = c.SearchCatalog("landsat", &SearchQuery{AcquiredBefore: day})
+	assert.NoError(err)
+	assert.Len(catalog.Features, 2) // the scene the server starts with, and day 0
+
+	// a collection is searched alone
+	catalog, err = c.GetInfoForCatalog("sentinel")
+	assert.NoError(err)
+	assert.Len(catalog.Features, 1)
+	assert.Equal("S2A", catalog.Features[0].Id)
+}
+
+func TestSTACItemFeature(t *testing.T) {
+	assert := assert.New(t)
+
+	item := &stacItem{}
+	err := json.Unmarshal([]byte(`{
+		"type": "Feature",
+		"id": "S2B_10SEG_20230601_0_L2A",
+		"bbox": [-123.0, 37.0, 0, -122.0, 38.0, 100],
+		"geometry": null,
+		"properties": {
+			"datetime": null,
+			"start_datetime": "2023-06-01T18:59:19Z",
+			"end_datetime": "2023-06-01T18:59:29Z",
+			"eo:cloud_cover": 12.5,
+			"instruments": ["msi"]
+		},
+		"links": [{"rel": "self", "href": "https://example.com/stac/items/S2B.json"}],
+		"assets": {
+			"red": {"href": "./B04.tif", "roles": ["data"]},
+			"nir": {"href": "https://cdn.example.com/B08.tif"},
+			"thumbnail": {"href": "./preview.png", "type": "image/png", "roles": ["thumbnail"]},
+			"metadata": {"href": "./MTD.xml"}
+		}
+	}`), item)
+	assert.NoError(err)
+
+	from, _ := url.Parse("https://example.com/stac/search")
+	feature, err := item.feature(item.baseURL(from))
+	assert.NoError(err)
+	assert.Nil(feature.Geometry)
+	assert.Equal([4]float64{-123, 37, -122, 38}, feature.Bbox)
+	assert.Equal("2023-06-01T18:59:19Z", feature.Properties.AcquiredDate)
+	assert.Equal(12.5, feature.Properties.CloudCover)
+	assert.Equal("msi", feature.Properties.SensorName)
+	assert.Equal("geotiff", feature.Properties.FileFormat)
+	assert.Equal(map[string]string{
+		"red": "https://example.com/stac/items/B04.tif",
+		"nir": "https://cdn.example.com/B08.tif",
+	}, feature.Properties.Bands)
+}
@@ -16,38 +16,44 @@ limitations under the License.
 package client
 
 import (
+	"context"
 	"encoding/base64"
-	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
+	"net/url"
 	"os"
-	"time"
+	"path/filepath"
+	"strings"
 )
 
+// The credential, if not nil, is added to the request.
 func doHttpGetJSON(
+	ctx context.Context,
+	client *http.Client,
 	url string,
-	timeout time.Duration,
+	credential Credential,
 	expectedStatus int,
 ) (string, error) {
 
-	client := &http.Client{Timeout: timeout}
-
-	/////log.Printf("URL: %s %s", "GET", url)
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return "", err
 	}
 
 	req.Header.Set("Content-Type", "application/json")
+	if credential != nil {
+		credential.Apply(req)
+	}
 
-	resp, err := client.Do(req)
+	resp, err := doRequest(client, req)
 	if err != nil {
 		return "", err
 	}
 
 	if resp.StatusCode != expectedStatus {
-		return "", fmt.Errorf("HTTP request failed with status %d", resp.StatusCode)
+		return "", newAPIError(resp)
 	}
 
 	responseBody, err := ioutil.ReadAll(resp.Body)
@@ -60,6 +66,8 @@ func doHttpGetJSON(
 }
 
 func doHttpGetJSONWithAuth(
+	ctx context.Context,
+	client *http.Client,
 	url string,
 	auth string,
 	expectedStatus int,
@@ -70,10 +78,47 @@ func doHttpGetJSONWithAuth(
 	}
 	auth64 := base64.StdEncoding.EncodeToString([]byte(auth))
 
-	client := &http.Client{}
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return "", err
+	}
+
+	req.Header.Set("Authorization", "Basic "+auth64)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := doRequest(client, req)
+	if err != nil {
+		return "", err
+	}
+
+	if resp.StatusCode != expectedStatus {
+		return "", newAPIError(resp)
+	}
 
-	//////log.Printf("URL: %s %s", "GET", url)
-	req, err := http.NewRequest("GET", url, nil)
+	responseBody, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return "", err
+	}
+
+	return string(responseBody), nil
+}
+
+func doHttpPostJSONWithAuth(
+	ctx context.Context,
+	client *http.Client,
+	url string,
+	auth string,
+	body string,
+	expectedStatus int,
+) (string, error) {
+
+	if auth[len(auth)-1:len(auth)] != ":" {
+		auth = auth + ":"
+	}
+	auth64 := base64.StdEncoding.EncodeToString([]byte(auth))
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, strings.NewReader(body))
 	if err != nil {
 		return "", err
 	}
@@ -81,13 +126,13 @@ func doHttpGetJSONWithAuth(
 	req.Header.Set("Authorization", "Basic "+auth64)
 	req.Header.Set("Content-Type", "application/json")
 
-	resp, err := client.Do(req)
+	resp, err := doRequest(client, req)
 	if err != nil {
 		return "", err
 	}
 
 	if resp.StatusCode != expectedStatus {
-		return "", fmt.Errorf("HTTP request failed with status %d", resp.StatusCode)
+		return "", newAPIError(resp)
 	}
 
 	responseBody, err := ioutil.ReadAll(resp.Body)
@@ -99,62 +144,172 @@ func doHttpGetJSONWithAuth(
 	return string(responseBody), nil
 }
 
-func doHttpGetBytes(
+func doHttpDeleteWithAuth(
+	ctx context.Context,
+	client *http.Client,
 	url string,
-	timeout time.Duration,
-) (int, []byte, error) {
+	auth string,
+	expectedStatus int,
+) (string, error) {
 
-	client := &http.Client{Timeout: timeout}
+	if auth[len(auth)-1:len(auth)] != ":" {
+		auth = auth + ":"
+	}
+	auth64 := base64.StdEncoding.EncodeToString([]byte(auth))
 
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := http.NewRequestWithContext(ctx, "DELETE", url, nil)
 	if err != nil {
-		return 0, nil, err
+		return "", err
 	}
 
-	resp, err := client.Do(req)
+	req.Header.Set("Authorization", "Basic "+auth64)
+
+	resp, err := doRequest(client, req)
 	if err != nil {
-		return 0, nil, err
+		return "", err
 	}
 
-	status := resp.StatusCode
+	if resp.StatusCode != expectedStatus {
+		return "", newAPIError(resp)
+	}
 
-	byts, err := ioutil.ReadAll(resp.Body)
+	responseBody, err := ioutil.ReadAll(resp.Body)
 	resp.Body.Close()
 	if err != nil {
-		return 0, nil, err
+		return "", err
 	}
 
-	return status, byts, nil
+	return string(responseBody), nil
+}
+
+// does the request, keeping secrets out of the error if it fails
+func doRequest(client *http.Client, req *http.Request) (*http.Response, error) {
+	resp, err := client.Do(req)
+	if ue, ok := err.(*url.Error); ok {
+		ue.URL = redactURL(req.URL)
+	}
+	return resp, err
+}
+
+// Streams the URL to the file at path, by way of "<path>.part". If a
+// partial file is left from an earlier attempt, only the rest of it is
+// requested, with a Range header. The client's overall Timeout is not
+// applied, as a large file may take longer than any one request should:
+// only its transport's ResponseHeaderTimeout is. The auth and progress
+// arguments are optional. If the context is cancelled, the partial file is
+// removed and the context's error returned. Returns the file size.
+func doHttpDownloadFile(
+	ctx context.Context,
+	client *http.Client,
+	url string,
+	auth string,
+	path string,
+	progress ProgressReporter,
+) (int64, error) {
+
+	streaming := *client
+	streaming.Timeout = 0
+
+	size, err := downloadFile(ctx, &streaming, url, auth, path, progress)
+	if err != nil && ctx.Err() != nil {
+		os.Remove(path + ".part")
+		err = ctx.Err()
+	}
+	if err != nil && progress != nil {
+		progress.Report(&Progress{File: filepath.Base(path), Total: -1, Finished: true, Err: err})
+	}
+	return size, err
 }
 
-// if any one field fails, the whole thing fails
-func ReadBeachfrontrcFields(fields []string) (map[string]string, error) {
-	user := os.Getenv("HOME")
-	file, err := os.Open(user + "/.beachfrontrc")
+func downloadFile(
+	ctx context.Context,
+	client *http.Client,
+	url string,
+	auth string,
+	path string,
+	progress ProgressReporter,
+) (int64, error) {
+
+	partial := path + ".part"
+
+	var offset int64
+	stat, err := os.Stat(partial)
+	if err == nil {
+		offset = stat.Size()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
-		return nil, err
+		return 0, err
+	}
+	if auth != "" {
+		if auth[len(auth)-1:len(auth)] != ":" {
+			auth = auth + ":"
+		}
+		req.Header.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(auth)))
+	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
 	}
 
-	byts, err := ioutil.ReadAll(file)
+	resp, err := doRequest(client, req)
 	if err != nil {
-		return nil, err
+		return 0, err
 	}
+	defer resp.Body.Close()
 
-	obj := &map[string]string{}
-	err = json.Unmarshal(byts, obj)
+	flags := os.O_WRONLY | os.O_CREATE
+	switch {
+	case resp.StatusCode == http.StatusPartialContent && offset > 0:
+		if !strings.HasPrefix(resp.Header.Get("Content-Range"), fmt.Sprintf("bytes %d-", offset)) {
+			return 0, fmt.Errorf("HTTP download resumed at the wrong offset: %s", resp.Header.Get("Content-Range"))
+		}
+		flags |= os.O_APPEND
+	case resp.StatusCode == http.StatusRequestedRangeNotSatisfiable && offset > 0:
+		// the partial file is already complete
+		if progress != nil {
+			progress.Report(&Progress{File: filepath.Base(path), Done: offset, Total: offset, Finished: true})
+		}
+		return offset, os.Rename(partial, path)
+	case resp.StatusCode == http.StatusOK:
+		// the server ignored the Range header: start over
+		flags |= os.O_TRUNC
+		offset = 0
+	default:
+		return 0, newAPIError(resp)
+	}
+
+	file, err := os.OpenFile(partial, flags, 0600)
 	if err != nil {
-		return nil, err
+		return 0, err
 	}
-	//log.Printf("OBJ: %v", obj)
-	results := map[string]string{}
 
-	for _, field := range fields {
-		value, ok := (*obj)[field]
-		if !ok || value == "" {
-			return nil, fmt.Errorf("Missing item in .beachfrontrc: '%s'", field)
+	var dst io.Writer = file
+	var meter *progressWriter
+	if progress != nil {
+		total := int64(-1)
+		if resp.ContentLength >= 0 {
+			total = offset + resp.ContentLength
 		}
-		results[field] = value
+		meter = newProgressWriter(progress, filepath.Base(path), offset, total)
+		dst = io.MultiWriter(file, meter)
+	}
+
+	n, err := io.Copy(dst, resp.Body)
+	cerr := file.Close()
+	if err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(partial, path)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	if meter != nil {
+		meter.finish(nil)
 	}
 
-	return results, nil
+	return offset + n, nil
 }
@@ -0,0 +1,157 @@
+/* Copyright 2017, RadiantBlue Technologies, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDownloadFileResume(t *testing.T) {
+	assert := assert.New(t)
+
+	content := bytes.Repeat([]byte("0123456789"), 1000)
+
+	ranges := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ranges = append(ranges, r.Header.Get("Range"))
+		http.ServeContent(w, r, "band.TIF", time.Time{}, bytes.NewReader(content))
+	}))
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "bf-client")
+	assert.NoError(err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "band.TIF")
+
+	// fresh download
+	n, err := doHttpDownloadFile(context.Background(), http.DefaultClient, server.URL, "", path, nil)
+	assert.NoError(err)
+	assert.EqualValues(len(content), n)
+	assert.Equal([]string{""}, ranges)
+
+	// resumed download
+	os.Remove(path)
+	err = ioutil.WriteFile(path+".part", content[:4000], 0600)
+	assert.NoError(err)
+
+	n, err = doHttpDownloadFile(context.Background(), http.DefaultClient, server.URL, "", path, nil)
+	assert.NoError(err)
+	assert.EqualValues(len(content), n)
+	assert.Equal("bytes=4000-", ranges[1])
+
+	byts, err := ioutil.ReadFile(path)
+	assert.NoError(err)
+	assert.Equal(content, byts)
+
+	_, err = os.Stat(path + ".part")
+	assert.True(os.IsNotExist(err))
+}
+
+func TestDownloadFileFailure(t *testing.T) {
+	assert := assert.New(t)
+
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "bf-client")
+	assert.NoError(err)
+	defer os.RemoveAll(dir)
+
+	_, err = doHttpDownloadFile(context.Background(), http.DefaultClient, server.URL, "", filepath.Join(dir, "band.TIF"), nil)
+	assert.Error(err)
+}
+
+func TestDownloadFileProgress(t *testing.T) {
+	assert := assert.New(t)
+
+	content := bytes.Repeat([]byte("0123456789"), 1000)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "band.TIF", time.Time{}, bytes.NewReader(content))
+	}))
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "bf-client")
+	assert.NoError(err)
+	defer os.RemoveAll(dir)
+
+	reports := []*Progress{}
+	progress := ProgressFunc(func(p *Progress) { reports = append(reports, p) })
+
+	_, err = doHttpDownloadFile(context.Background(), http.DefaultClient, server.URL, "", filepath.Join(dir, "band.TIF"), progress)
+	assert.NoError(err)
+
+	last := reports[len(reports)-1]
+	assert.Equal("band.TIF", last.File)
+	assert.True(last.Finished)
+	assert.NoError(last.Err)
+	assert.EqualValues(len(content), last.Done)
+	assert.EqualValues(len(content), last.Total)
+
+	failing := httptest.NewServer(http.NotFoundHandler())
+	defer failing.Close()
+
+	reports = []*Progress{}
+	_, err = doHttpDownloadFile(context.Background(), http.DefaultClient, failing.URL, "", filepath.Join(dir, "bad.TIF"), progress)
+	assert.Error(err)
+	assert.Len(reports, 1)
+	assert.True(reports[0].Finished)
+	assert.Error(reports[0].Err)
+}
+
+func TestDownloadFileCancel(t *testing.T) {
+	assert := assert.New(t)
+
+	started := make(chan bool)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "10000")
+		w.Write(bytes.Repeat([]byte("x"), 1000))
+		w.(http.Flusher).Flush()
+		close(started)
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "bf-client")
+	assert.NoError(err)
+	defer os.RemoveAll(dir)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-started
+		cancel()
+	}()
+
+	path := filepath.Join(dir, "band.TIF")
+	_, err = doHttpDownloadFile(ctx, http.DefaultClient, server.URL, "", path, nil)
+	assert.Equal(context.Canceled, err)
+
+	_, err = os.Stat(path + ".part")
+	assert.True(os.IsNotExist(err))
+	_, err = os.Stat(path)
+	assert.True(os.IsNotExist(err))
+}
@@ -0,0 +1,238 @@
+/* Copyright 2017, RadiantBlue Technologies, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package geojson holds typed GeoJSON (RFC 7946) geometries, features and
+// feature collections, as returned by the Beachfront services.
+package geojson
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Position is a single coordinate: x (longitude), y (latitude) and an
+// optional elevation.
+type Position []float64
+
+// Shape is implemented by each of the geometry types.
+type Shape interface {
+	Type() string
+}
+
+type Point struct {
+	Coordinates Position
+}
+
+type MultiPoint struct {
+	Coordinates []Position
+}
+
+type LineString struct {
+	Coordinates []Position
+}
+
+type MultiLineString struct {
+	Coordinates [][]Position
+}
+
+// Polygon is a list of linear rings, the first being the exterior.
+type Polygon struct {
+	Coordinates [][]Position
+}
+
+type MultiPolygon struct {
+	Coordinates [][][]Position
+}
+
+type GeometryCollection struct {
+	Geometries []*Geometry
+}
+
+func (Point) Type() string              { return "Point" }
+func (MultiPoint) Type() string         { return "MultiPoint" }
+func (LineString) Type() string         { return "LineString" }
+func (MultiLineString) Type() string    { return "MultiLineString" }
+func (Polygon) Type() string            { return "Polygon" }
+func (MultiPolygon) Type() string       { return "MultiPolygon" }
+func (GeometryCollection) Type() string { return "GeometryCollection" }
+
+// implemented by every shape except GeometryCollection
+type coordinator interface {
+	coordinates() interface{}
+}
+
+func (s Point) coordinates() interface{}           { return s.Coordinates }
+func (s MultiPoint) coordinates() interface{}      { return s.Coordinates }
+func (s LineString) coordinates() interface{}      { return s.Coordinates }
+func (s MultiLineString) coordinates() interface{} { return s.Coordinates }
+func (s Polygon) coordinates() interface{}         { return s.Coordinates }
+func (s MultiPolygon) coordinates() interface{}    { return s.Coordinates }
+
+//---------------------------------------------------------------------
+
+// Geometry holds any one of the shapes. It is what appears in a feature's
+// "geometry" member: decoding picks the shape from the "type" member.
+type Geometry struct {
+	Shape
+}
+
+// Type returns the GeoJSON type name, or "" if there is no shape.
+func (g *Geometry) Type() string {
+	if g == nil || g.Shape == nil {
+		return ""
+	}
+	return g.Shape.Type()
+}
+
+// the wire form of every geometry type
+type rawGeometry struct {
+	Type        string          `json:"type"`
+	Coordinates json.RawMessage `json:"coordinates,omitempty"`
+	Geometries  []*Geometry     `json:"geometries,omitempty"`
+}
+
+func (g *Geometry) UnmarshalJSON(b []byte) error {
+	raw := &rawGeometry{}
+	err := json.Unmarshal(b, raw)
+	if err != nil {
+		return err
+	}
+
+	if raw.Type == "GeometryCollection" {
+		if raw.Geometries == nil {
+			return fmt.Errorf("geojson: GeometryCollection has no geometries")
+		}
+		g.Shape = &GeometryCollection{Geometries: raw.Geometries}
+		return nil
+	}
+
+	if raw.Coordinates == nil {
+		return fmt.Errorf("geojson: %s has no coordinates", raw.Type)
+	}
+
+	switch raw.Type {
+	case "Point":
+		p := &Point{}
+		err = decodeCoordinates(raw, &p.Coordinates, 0)
+		g.Shape = p
+	case "MultiPoint":
+		p := &MultiPoint{}
+		err = decodeCoordinates(raw, &p.Coordinates, 1)
+		g.Shape = p
+	case "LineString":
+		p := &LineString{}
+		err = decodeCoordinates(raw, &p.Coordinates, 1)
+		g.Shape = p
+	case "MultiLineString":
+		p := &MultiLineString{}
+		err = decodeCoordinates(raw, &p.Coordinates, 2)
+		g.Shape = p
+	case "Polygon":
+		p := &Polygon{}
+		err = decodeCoordinates(raw, &p.Coordinates, 2)
+		g.Shape = p
+	case "MultiPolygon":
+		p := &MultiPolygon{}
+		err = decodeCoordinates(raw, &p.Coordinates, 3)
+		g.Shape = p
+	default:
+		return fmt.Errorf("geojson: unknown geometry type '%s'", raw.Type)
+	}
+
+	return err
+}
+
+// decodes the coordinates into dst, then checks that every position,
+// found at the given nesting depth, has at least x and y
+func decodeCoordinates(raw *rawGeometry, dst interface{}, depth int) error {
+	err := json.Unmarshal(raw.Coordinates, dst)
+	if err != nil {
+		return fmt.Errorf("geojson: invalid %s coordinates: %s", raw.Type, err.Error())
+	}
+
+	var generic interface{}
+	err = json.Unmarshal(raw.Coordinates, &generic)
+	if err != nil {
+		return err
+	}
+	if !checkPositions(generic, depth) {
+		return fmt.Errorf("geojson: %s has a position with fewer than two values", raw.Type)
+	}
+	return nil
+}
+
+func checkPositions(v interface{}, depth int) bool {
+	list, ok := v.([]interface{})
+	if !ok {
+		return false
+	}
+	if depth == 0 {
+		return len(list) >= 2
+	}
+	for _, item := range list {
+		if !checkPositions(item, depth-1) {
+			return false
+		}
+	}
+	return true
+}
+
+func (g Geometry) MarshalJSON() ([]byte, error) {
+	if g.Shape == nil {
+		return []byte("null"), nil
+	}
+
+	var geometries []*Geometry
+	switch s := g.Shape.(type) {
+	case *GeometryCollection:
+		geometries = s.Geometries
+	case GeometryCollection:
+		geometries = s.Geometries
+	case coordinator:
+		byts, err := json.Marshal(s.coordinates())
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(&rawGeometry{Type: g.Shape.Type(), Coordinates: byts})
+	default:
+		return nil, fmt.Errorf("geojson: unsupported shape %T", g.Shape)
+	}
+
+	if geometries == nil {
+		geometries = []*Geometry{}
+	}
+	return json.Marshal(&struct {
+		Type       string      `json:"type"`
+		Geometries []*Geometry `json:"geometries"`
+	}{"GeometryCollection", geometries})
+}
+
+//---------------------------------------------------------------------
+
+// Feature is a GeoJSON Feature with untyped properties. Services with known
+// properties declare their own feature types using Geometry directly.
+type Feature struct {
+	Type       string                 `json:"type"`
+	Id         interface{}            `json:"id,omitempty"`
+	Geometry   *Geometry              `json:"geometry"`
+	Properties map[string]interface{} `json:"properties"`
+	Bbox       []float64              `json:"bbox,omitempty"`
+}
+
+type FeatureCollection struct {
+	Type     string     `json:"type"`
+	Features []*Feature `json:"features"`
+	Bbox     []float64  `json:"bbox,omitempty"`
+}
@@ -0,0 +1,117 @@
+/* Copyright 2017, RadiantBlue Technologies, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package geojson
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGeometryRoundTrip(t *testing.T) {
+	assert := assert.New(t)
+
+	tests := []string{
+		`{"type":"Point","coordinates":[1.5,2]}`,
+		`{"type":"MultiPoint","coordinates":[[1,2],[3,4]]}`,
+		`{"type":"LineString","coordinates":[[1,2],[3,4,5]]}`,
+		`{"type":"MultiLineString","coordinates":[[[1,2],[3,4]],[[5,6],[7,8]]]}`,
+		`{"type":"Polygon","coordinates":[[[0,0],[1,0],[1,1],[0,0]]]}`,
+		`{"type":"MultiPolygon","coordinates":[[[[0,0],[1,0],[1,1],[0,0]]],[[[5,5],[6,5],[6,6],[5,5]]]]}`,
+		`{"type":"GeometryCollection","geometries":[{"type":"Point","coordinates":[1,2]},{"type":"LineString","coordinates":[[1,2],[3,4]]}]}`,
+		`{"type":"GeometryCollection","geometries":[]}`,
+	}
+
+	for _, jsn := range tests {
+		g := &Geometry{}
+		err := json.Unmarshal([]byte(jsn), g)
+		assert.NoError(err, jsn)
+
+		byts, err := json.Marshal(g)
+		assert.NoError(err, jsn)
+		assert.JSONEq(jsn, string(byts))
+	}
+}
+
+func TestGeometryTypes(t *testing.T) {
+	assert := assert.New(t)
+
+	g := &Geometry{}
+	err := json.Unmarshal([]byte(`{"type":"Polygon","coordinates":[[[0,0],[1,0],[1,1],[0,0]]]}`), g)
+	assert.NoError(err)
+	assert.Equal("Polygon", g.Type())
+
+	poly, ok := g.Shape.(*Polygon)
+	assert.True(ok)
+	assert.Len(poly.Coordinates, 1)
+	assert.Equal(Position{1, 1}, poly.Coordinates[0][2])
+
+	err = json.Unmarshal([]byte(`{"type":"GeometryCollection","geometries":[{"type":"Point","coordinates":[1,2]}]}`), g)
+	assert.NoError(err)
+	coll, ok := g.Shape.(*GeometryCollection)
+	assert.True(ok)
+	assert.Equal("Point", coll.Geometries[0].Type())
+
+	var nilGeometry *Geometry
+	assert.Equal("", nilGeometry.Type())
+
+	byts, err := json.Marshal(&Geometry{Point{Position{3, 4}}})
+	assert.NoError(err)
+	assert.JSONEq(`{"type":"Point","coordinates":[3,4]}`, string(byts))
+}
+
+func TestGeometryErrors(t *testing.T) {
+	assert := assert.New(t)
+
+	tests := []string{
+		`{"type":"Circle","coordinates":[1,2]}`,
+		`{"type":"Point"}`,
+		`{"type":"Point","coordinates":[1]}`,
+		`{"type":"Point","coordinates":[[1,2]]}`,
+		`{"type":"Polygon","coordinates":[[1,2],[3,4]]}`,
+		`{"type":"LineString","coordinates":[[1,2],[3]]}`,
+		`{"type":"GeometryCollection"}`,
+	}
+
+	for _, jsn := range tests {
+		g := &Geometry{}
+		err := json.Unmarshal([]byte(jsn), g)
+		assert.Error(err, jsn)
+	}
+}
+
+func TestFeatureCollection(t *testing.T) {
+	assert := assert.New(t)
+
+	const jsn = `{
+		"type": "FeatureCollection",
+		"features": [
+			{"type": "Feature", "id": "a", "geometry": {"type": "LineString", "coordinates": [[1,2],[3,4]]}, "properties": {"name": "x"}},
+			{"type": "Feature", "geometry": null, "properties": null}
+		]
+	}`
+
+	fc := &FeatureCollection{}
+	err := json.Unmarshal([]byte(jsn), fc)
+	assert.NoError(err)
+
+	assert.Len(fc.Features, 2)
+	assert.Equal("a", fc.Features[0].Id)
+	assert.Equal("LineString", fc.Features[0].Geometry.Type())
+	assert.Equal("x", fc.Features[0].Properties["name"])
+	assert.Nil(fc.Features[1].Geometry)
+}